@@ -0,0 +1,154 @@
+package dngn
+
+import (
+	"context"
+	"math"
+)
+
+// The names of the biomes returned from NewDefaultBiomes().
+const (
+	BiomeOcean               = "ocean"
+	BiomeBeach               = "beach"
+	BiomeTundra              = "tundra"
+	BiomeTaiga               = "taiga"
+	BiomeGrassland           = "grassland"
+	BiomeForest              = "forest"
+	BiomeTemperateRainforest = "temperate rainforest"
+	BiomeDesert              = "desert"
+	BiomeSavanna             = "savanna"
+	BiomeTropicalRainforest  = "tropical rainforest"
+	BiomeMountain            = "mountain"
+	BiomeSnow                = "snow"
+)
+
+// Biome is an entry in the lookup table used by Layout.GenerateBiomes(). MaxElevation, MaxTemperature, and MaxMoisture are the
+// highest values (from 0 to 1) of each map that fall into the biome; a cell is given the first biome in the table that its
+// values fit into, so the table should go from the most specific biomes to the most general ones.
+type Biome struct {
+	Name           string
+	Value          rune
+	MaxElevation   float64
+	MaxTemperature float64
+	MaxMoisture    float64
+}
+
+// NewDefaultBiomes returns a Whittaker-style biome table. Low cells are ocean ('~') and beaches ('.'), and high cells are
+// mountains ('^') topped with snow ('*'). In between, the biome depends on the temperature and moisture:
+//
+//	             dry                  moist          wet
+//	cold         tundra ('-')                        taiga ('Y')
+//	temperate    grassland ('"')      forest ('T')   temperate rainforest ('R')
+//	hot          desert (':')         savanna (',')  tropical rainforest ('&')
+func NewDefaultBiomes() []Biome {
+	return []Biome{
+		{Name: BiomeOcean, Value: '~', MaxElevation: 0.35, MaxTemperature: 1, MaxMoisture: 1},
+		{Name: BiomeBeach, Value: '.', MaxElevation: 0.4, MaxTemperature: 1, MaxMoisture: 1},
+
+		{Name: BiomeTundra, Value: '-', MaxElevation: 0.75, MaxTemperature: 0.25, MaxMoisture: 0.5},
+		{Name: BiomeTaiga, Value: 'Y', MaxElevation: 0.75, MaxTemperature: 0.25, MaxMoisture: 1},
+
+		{Name: BiomeGrassland, Value: '"', MaxElevation: 0.75, MaxTemperature: 0.6, MaxMoisture: 0.35},
+		{Name: BiomeForest, Value: 'T', MaxElevation: 0.75, MaxTemperature: 0.6, MaxMoisture: 0.7},
+		{Name: BiomeTemperateRainforest, Value: 'R', MaxElevation: 0.75, MaxTemperature: 0.6, MaxMoisture: 1},
+
+		{Name: BiomeDesert, Value: ':', MaxElevation: 0.75, MaxTemperature: 1, MaxMoisture: 0.3},
+		{Name: BiomeSavanna, Value: ',', MaxElevation: 0.75, MaxTemperature: 1, MaxMoisture: 0.6},
+		{Name: BiomeTropicalRainforest, Value: '&', MaxElevation: 0.75, MaxTemperature: 1, MaxMoisture: 1},
+
+		{Name: BiomeMountain, Value: '^', MaxElevation: 0.9, MaxTemperature: 1, MaxMoisture: 1},
+		{Name: BiomeSnow, Value: '*', MaxElevation: 1, MaxTemperature: 1, MaxMoisture: 1},
+	}
+}
+
+// BiomeFields are the maps Layout.GenerateBiomes() classifies cells with. Elevation is required. If Temperature is nil, the
+// temperature depends on latitude instead, going from hot in the middle row of the Layout to cold at the top and bottom edges.
+// If Moisture is nil, every cell is moderately moist (0.5).
+type BiomeFields struct {
+	Elevation   *Noise
+	Temperature *Noise
+	Moisture    *Noise
+}
+
+// GenerateBiomes fills the Layout with biomes by sampling the elevation, temperature, and moisture maps at each cell, and drawing
+// the cell with the first biome in the table provided that the values fit into (see Biome and NewDefaultBiomes()). Noise values are
+// mapped from -1 - 1 to 0 - 1 before being looked up. Cells that don't fit into any biome are left as they are.
+// GenerateBiomes returns a Selection of the cells in each biome, by the biome's name (biomes with the same name share a
+// Selection), or an error if the table is empty, Elevation is nil, or one of the fields' settings is invalid.
+// GenerateBiomes is the same as GenerateBiomesContext() with a background context.
+func (layout *Layout) GenerateBiomes(fields BiomeFields, biomes []Biome) (map[string]Selection, error) {
+	return layout.GenerateBiomesContext(context.Background(), fields, biomes)
+}
+
+// GenerateBiomesContext works like GenerateBiomes(), but stops and returns an error if the context is cancelled or the Layout's
+// StepBudget runs out before generation finishes.
+func (layout *Layout) GenerateBiomesContext(ctx context.Context, fields BiomeFields, biomes []Biome) (map[string]Selection, error) {
+
+	if err := layout.validateSize("GenerateBiomes"); err != nil {
+		return nil, err
+	}
+
+	if len(biomes) == 0 {
+		return nil, invalidArgument("GenerateBiomes", "no biomes given")
+	}
+
+	if fields.Elevation == nil {
+		return nil, invalidArgument("GenerateBiomes", "no elevation field given")
+	}
+
+	for _, field := range []*Noise{fields.Elevation, fields.Temperature, fields.Moisture} {
+		if field != nil {
+			if err := field.validate("GenerateBiomes"); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	gen := layout.generation(ctx, "biomes")
+
+	// Each biome is looked up as a band with a Max for elevation, temperature, and moisture, in that order.
+	bands := make([]NoiseBand, len(biomes))
+	selections := map[string]Selection{}
+
+	for i, biome := range biomes {
+		bands[i] = NoiseBand{Value: biome.Value, Max: []float64{biome.MaxElevation, biome.MaxTemperature, biome.MaxMoisture}}
+		if _, exists := selections[biome.Name]; !exists {
+			selections[biome.Name] = Selection{Layout: layout, Cells: map[Position]bool{}}
+		}
+	}
+
+	values := make([]float64, 3)
+
+	for y := 0; y < layout.Height; y++ {
+
+		latitude := 1 - math.Abs((float64(y)+0.5)/float64(layout.Height)*2-1)
+
+		for x := 0; x < layout.Width; x++ {
+
+			values[0] = fields.Elevation.normalized(x, y)
+
+			values[1] = latitude
+			if fields.Temperature != nil {
+				values[1] = fields.Temperature.normalized(x, y)
+			}
+
+			values[2] = 0.5
+			if fields.Moisture != nil {
+				values[2] = fields.Moisture.normalized(x, y)
+			}
+
+			if band := noiseBandFor(bands, values); band >= 0 {
+				layout.Set(x, y, biomes[band].Value)
+				selections[biomes[band].Name].Cells[Position{x, y}] = true
+			}
+
+		}
+
+		if err := gen.step(GenerationStep{Kind: StepFill, X: 0, Y: y, W: layout.Width, H: 1}); err != nil {
+			return nil, err
+		}
+
+	}
+
+	return selections, nil
+
+}
@@ -0,0 +1,66 @@
+package dngn
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestBiomes checks that the same seeds draw the same map, that each biome's Selection matches the cells drawn with it, and that
+// the top and bottom rows are too cold for hot biomes when there's no temperature field.
+func TestBiomes(t *testing.T) {
+
+	generate := func(seed int64) (*Layout, map[string]Selection) {
+
+		layout := NewLayout(60, 40)
+
+		fields := BiomeFields{
+			Elevation: NewNoise(NoiseSimplex, seed),
+			Moisture:  NewNoise(NoiseSimplex, seed+1),
+		}
+
+		selections, err := layout.GenerateBiomes(fields, NewDefaultBiomes())
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+
+		return layout, selections
+
+	}
+
+	values := map[string]rune{}
+	for _, biome := range NewDefaultBiomes() {
+		values[biome.Name] = biome.Value
+	}
+
+	for seed := int64(1); seed <= 3; seed++ {
+
+		first, selections := generate(seed)
+		second, _ := generate(seed)
+
+		if first.DataToString() != second.DataToString() {
+			t.Errorf("seed %d: the same seed drew different maps", seed)
+		}
+
+		for name, selection := range selections {
+			for cell := range selection.Cells {
+				if value := first.Get(cell.X, cell.Y); value != values[name] {
+					t.Errorf("seed %d: %v is in the %s biome, but was drawn as %q", seed, cell, name, value)
+				}
+			}
+		}
+
+		for _, name := range []string{BiomeDesert, BiomeSavanna, BiomeTropicalRainforest} {
+			for cell := range selections[name].Cells {
+				if cell.Y == 0 || cell.Y == first.Height-1 {
+					t.Errorf("seed %d: %v is on the edge of the map, but is in the %s biome", seed, cell, name)
+				}
+			}
+		}
+
+	}
+
+	if _, err := NewLayout(20, 20).GenerateBiomes(BiomeFields{}, NewDefaultBiomes()); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument without an elevation field, got %v", err)
+	}
+
+}
@@ -1,10 +1,162 @@
 package dngn
 
+import "fmt"
+
+// bspSplitAttempts is how many times in a row GenerateBSP will try (and fail) to split a room before considering that room finished.
+const bspSplitAttempts = 10
+
 type BSPOptions struct {
 	WallValue       rune // Rune value to use for walls
 	SplitCount      int  // How many times to split the layout
 	DoorValue       rune // Rune value to use for doors / doorways
-	MinimumRoomSize int  // Minimum allowed size of each room within the generated BSP layout
+	MinimumRoomSize int  // Minimum allowed size of each room (partition, if CarveRooms is true) within the generated BSP layout
+
+	// If CarveRooms is true, rather than drawing walls between partitions, the Layout is filled with walls and a randomly sized
+	// room is carved out inside each partition. Sibling partitions are then connected with corridors, going up the partition
+	// tree, so every room is reachable. Doors are placed where corridors enter rooms. The Rooms returned are the carved rooms.
+	CarveRooms bool
+	// RoomPadding is the minimum number of wall cells between a carved room and the edges of its partition when CarveRooms is true.
+	RoomPadding int
+
+	Doors BSPDoorPolicy // Controls how many doors are placed between rooms, and where.
+
+	// If EnsureConnected is true, GenerateBSP makes sure every room can be reached from every other room (through the rooms'
+	// Connected lists), adding doors where needed, even to border rooms when Doors.SkipBorderRooms is true. If the rooms can't be
+	// connected (for example, when MinimumRoomSize is 0 and some rooms are too thin to have any floor), an error wrapping
+	// ErrCannotConverge is returned.
+	EnsureConnected bool
+}
+
+// BSPDoorPolicy controls how Layout.GenerateBSP() places doors between rooms.
+// Doors are placed on a random selection of the walls shared between neighboring rooms that's just enough to connect all of the
+// rooms together; the rest of the shared walls only get doors according to LoopChance. When CarveRooms is true, rooms are connected
+// by corridors instead, and only LoopChance applies (as the chance to carve an extra corridor between sibling partitions).
+type BSPDoorPolicy struct {
+	DoorsPerWall    int           // How many doors to place on each shared wall that gets doors, if there's room. Values under 1 are treated as 1.
+	DoorWidth       int           // How wide each door is, in cells. Values under 1 are treated as 1.
+	LoopChance      float32       // The chance (0 - 1) for a wall between two rooms that are already connected to get doors anyway, creating a loop.
+	Placement       DoorPlacement // Where doors are placed along each wall.
+	SkipBorderRooms bool          // If true, rooms touching the edges of the Layout aren't guaranteed any doors, and only get them through LoopChance.
+}
+
+// DoorPlacement indicates where along a wall Layout.GenerateBSP() places doors.
+type DoorPlacement int
+
+const (
+	DoorPlacementRandom   DoorPlacement = iota // Doors are placed at random spots along the wall.
+	DoorPlacementCentered                      // Doors are placed in the center of the wall (spaced out evenly if there's more than one).
+	DoorPlacementCorners                       // Doors are placed near the ends of the wall.
+)
+
+// BSPResult is the result of generating a Layout using Layout.GenerateBSP().
+type BSPResult struct {
+	RoomGraph          // The rooms generated, and the doors between them.
+	Tree      *BSPNode // The root of the partition tree, covering the entire Layout.
+}
+
+// BSPNode is a node in the partition tree built by Layout.GenerateBSP(). X, Y, W, and H are the area of the Layout the node covers.
+// Nodes that were split have two children (Left and Right), each covering part of the node's area; Left is always the top or
+// left side of the split. Vertical is whether the node was split with a vertical line (so Left and Right are side by side), and
+// SplitPosition is the X (if vertical) or Y position of the split, which is also where Right begins. Nodes that weren't split are
+// leaves, and contain the Room generated in them. Parent is the node this node was split from, and is nil for the root.
+type BSPNode struct {
+	X, Y, W, H    int
+	Parent        *BSPNode
+	Left, Right   *BSPNode
+	Vertical      bool
+	SplitPosition int
+	Room          *Room
+}
+
+// Depth returns how many splits it took to get to this BSPNode from the root of the partition tree (which has a depth of 0).
+func (node *BSPNode) Depth() int {
+	depth := 0
+	for parent := node.Parent; parent != nil; parent = parent.Parent {
+		depth++
+	}
+	return depth
+}
+
+// Sibling returns the other node split from this BSPNode's parent, or nil if this node is the root of the tree.
+func (node *BSPNode) Sibling() *BSPNode {
+	if node.Parent == nil {
+		return nil
+	}
+	if node.Parent.Left == node {
+		return node.Parent.Right
+	}
+	return node.Parent.Left
+}
+
+// IsLeaf returns if the BSPNode is a leaf (i.e. it wasn't split).
+func (node *BSPNode) IsLeaf() bool {
+	return node.Left == nil && node.Right == nil
+}
+
+// Leaves returns the leaf nodes under this BSPNode (including this node, if it's a leaf itself).
+func (node *BSPNode) Leaves() []*BSPNode {
+
+	if node.IsLeaf() {
+		return []*BSPNode{node}
+	}
+
+	leaves := []*BSPNode{}
+
+	for _, child := range []*BSPNode{node.Left, node.Right} {
+		if child != nil {
+			leaves = append(leaves, child.Leaves()...)
+		}
+	}
+
+	return leaves
+
+}
+
+// Rooms returns the Rooms contained in the leaves under this BSPNode.
+func (node *BSPNode) Rooms() []*Room {
+
+	rooms := []*Room{}
+
+	for _, leaf := range node.Leaves() {
+		if leaf.Room != nil {
+			rooms = append(rooms, leaf.Room)
+		}
+	}
+
+	return rooms
+
+}
+
+// validate returns an error if the BSPOptions can't be used to generate a map.
+func (options BSPOptions) validate() error {
+
+	if options.SplitCount < 0 {
+		return invalidArgument("GenerateBSP", "SplitCount can't be negative, got %d", options.SplitCount)
+	}
+
+	if options.MinimumRoomSize < 0 {
+		return invalidArgument("GenerateBSP", "MinimumRoomSize can't be negative, got %d", options.MinimumRoomSize)
+	}
+
+	if options.RoomPadding < 0 {
+		return invalidArgument("GenerateBSP", "RoomPadding can't be negative, got %d", options.RoomPadding)
+	}
+
+	// This is written so that NaN fails it too.
+	if !(options.Doors.LoopChance >= 0 && options.Doors.LoopChance <= 1) {
+		return invalidArgument("GenerateBSP", "Doors.LoopChance must be between 0 and 1, got %v", options.Doors.LoopChance)
+	}
+
+	if options.Doors.Placement < DoorPlacementRandom || options.Doors.Placement > DoorPlacementCorners {
+		return invalidArgument("GenerateBSP", "unknown Doors.Placement %d", options.Doors.Placement)
+	}
+
+	if options.WallValue == ' ' {
+		return invalidArgument("GenerateBSP", "WallValue can't be ' ', as that's used for the rooms' floors")
+	}
+
+	return nil
+
 }
 
 func NewDefaultBSPOptions() BSPOptions {
@@ -14,129 +166,505 @@ func NewDefaultBSPOptions() BSPOptions {
 		SplitCount:      10,
 		DoorValue:       '#',
 		MinimumRoomSize: 4,
+		RoomPadding:     1,
+		Doors: BSPDoorPolicy{
+			DoorsPerWall: 1,
+			DoorWidth:    1,
+			LoopChance:   0.25,
+			Placement:    DoorPlacementRandom,
+		},
+		EnsureConnected: true,
+	}
+}
+
+// splitStep returns a GenerationStep describing an attempt to split the parent Room at the given position.
+func splitStep(kind StepKind, parent *Room, vertical bool, position int) GenerationStep {
+	return GenerationStep{
+		Kind:          kind,
+		X:             parent.X,
+		Y:             parent.Y,
+		W:             parent.W,
+		H:             parent.H,
+		Vertical:      vertical,
+		SplitPosition: position,
+		Room:          parent,
+	}
+}
+
+// bspWall is a stretch of wall shared between two neighboring Rooms, along with the spots on it where doors can go.
+type bspWall struct {
+	A, B  *Room
+	Spots []Position
+}
+
+// placeBSPDoors places doors on the walls between the result's rooms according to the options' door policy.
+func (layout *Layout) placeBSPDoors(gen *generation, result *BSPResult, options BSPOptions) error {
+
+	neighborAt := func(x, y int) *Room {
+		for _, other := range result.Rooms {
+			if other.Contains(x, y) {
+				return other
+			}
+		}
+		return nil
+	}
+
+	// Every wall shared between two rooms is the top or left wall of one of them (as the walls are always on the top and left
+	// sides), so we can find all of them by looking at just those sides. Door spots need open floor on both sides.
+	walls := []*bspWall{}
+
+	for _, room := range result.Rooms {
+
+		roomWalls := map[*Room]*bspWall{}
+
+		addSpot := func(spot Position, neighbor *Room) {
+			if neighbor == nil || neighbor == room {
+				return
+			}
+			if _, exists := roomWalls[neighbor]; !exists {
+				roomWalls[neighbor] = &bspWall{A: room, B: neighbor}
+				walls = append(walls, roomWalls[neighbor])
+			}
+			roomWalls[neighbor].Spots = append(roomWalls[neighbor].Spots, spot)
+		}
+
+		if room.Y > 0 {
+			for x := room.X; x < room.X+room.W; x++ {
+				if layout.Get(x, room.Y-1) == ' ' && layout.Get(x, room.Y+1) == ' ' {
+					addSpot(Position{x, room.Y}, neighborAt(x, room.Y-1))
+				}
+			}
+		}
+
+		if room.X > 0 {
+			for y := room.Y; y < room.Y+room.H; y++ {
+				if layout.Get(room.X-1, y) == ' ' && layout.Get(room.X+1, y) == ' ' {
+					addSpot(Position{room.X, y}, neighborAt(room.X-1, y))
+				}
+			}
+		}
+
+	}
+
+	layout.RNG.Shuffle(len(walls), func(i, j int) { walls[i], walls[j] = walls[j], walls[i] })
+
+	onBorder := func(room *Room) bool {
+		return room.X == 0 || room.Y == 0 || room.X+room.W >= layout.Width || room.Y+room.H >= layout.Height
+	}
+
+	// Walls that connect two groups of rooms that aren't yet connected always get doors (so all of the rooms get connected);
+	// the rest only get doors according to the loop chance.
+	groups := roomGroups{}
+
+	for _, wall := range walls {
+
+		needed := groups.find(wall.A) != groups.find(wall.B)
+
+		if options.Doors.SkipBorderRooms && (onBorder(wall.A) || onBorder(wall.B)) {
+			needed = false
+		}
+
+		if !needed && layout.RNG.Float32() >= options.Doors.LoopChance {
+			continue
+		}
+
+		placed, err := layout.placeBSPWallDoors(gen, result, wall, options)
+		if err != nil {
+			return err
+		}
+
+		if placed {
+			groups.union(wall.A, wall.B)
+		}
+
+	}
+
+	if options.EnsureConnected {
+
+		// Any walls between rooms that still aren't connected get doors now.
+		for _, wall := range walls {
+
+			if groups.find(wall.A) == groups.find(wall.B) {
+				continue
+			}
+
+			placed, err := layout.placeBSPWallDoors(gen, result, wall, options)
+			if err != nil {
+				return err
+			}
+
+			if placed {
+				groups.union(wall.A, wall.B)
+			}
+
+		}
+
+		if !result.Connected() {
+			return fmt.Errorf("dngn: GenerateBSP: some rooms have no space for doors, so they can't all be connected: %w", ErrCannotConverge)
+		}
+
+	}
+
+	return nil
+
+}
+
+// placeBSPWallDoors places the doors on a wall shared by two rooms, returning if any doors were placed.
+func (layout *Layout) placeBSPWallDoors(gen *generation, result *BSPResult, wall *bspWall, options BSPOptions) (bool, error) {
+
+	width := options.Doors.DoorWidth
+	if width < 1 {
+		width = 1
+	}
+
+	count := options.Doors.DoorsPerWall
+	if count < 1 {
+		count = 1
+	}
+
+	// Doors can start at any spot with enough room after it (in a straight line along the wall) for the door's width.
+	starts := []int{}
+
+	for width > 0 && len(starts) == 0 {
+
+		for i := range wall.Spots {
+			if i+width-1 < len(wall.Spots) && wall.Spots[i+width-1].X-wall.Spots[i].X+wall.Spots[i+width-1].Y-wall.Spots[i].Y == width-1 {
+				starts = append(starts, i)
+			}
+		}
+
+		// The wall's too short for doors this wide, so try narrower ones.
+		if len(starts) == 0 {
+			width--
+		}
+
 	}
+
+	if len(starts) == 0 {
+		return false, nil
+	}
+
+	// A wall can't hold more doors than it has spots to start them from.
+	count = minInt(count, len(starts))
+
+	chosen := []int{}
+
+	switch options.Doors.Placement {
+
+	case DoorPlacementCentered:
+		for i := 0; i < count; i++ {
+			chosen = append(chosen, starts[(i+1)*len(starts)/(count+1)])
+		}
+
+	case DoorPlacementCorners:
+		for i := 0; i < count; i++ {
+			if i%2 == 0 {
+				chosen = append(chosen, starts[i/2])
+			} else {
+				chosen = append(chosen, starts[len(starts)-1-i/2])
+			}
+		}
+
+	default:
+		layout.RNG.Shuffle(len(starts), func(i, j int) { starts[i], starts[j] = starts[j], starts[i] })
+		chosen = starts[:count]
+
+	}
+
+	placed := map[Position]bool{}
+
+	for _, start := range chosen {
+
+		for _, spot := range wall.Spots[clampInt(start, 0, len(wall.Spots)-1):clampInt(start+width, 0, len(wall.Spots))] {
+
+			if placed[spot] {
+				continue
+			}
+
+			placed[spot] = true
+
+			layout.Set(spot.X, spot.Y, options.DoorValue)
+
+			result.Doors = append(result.Doors, connectRooms(wall.A, wall.B, spot))
+
+			if err := gen.step(GenerationStep{Kind: StepDoor, X: spot.X, Y: spot.Y, W: 1, H: 1, Room: wall.A}); err != nil {
+				return false, err
+			}
+
+		}
+
+	}
+
+	return true, nil
+
 }
 
-// BSPRoom represents a room generated through Layout.GenerateBSP().
-type BSPRoom struct {
-	X, Y, W, H  int        // X, Y, Width, and Height of the BSPRoom.
-	Connected   []*BSPRoom // The BSPRooms this room is connected to.
-	Traversable bool       // Whether the BSPRoom is traversable when using CountHopsTo().
+// roomGroups keeps track of which groups of Rooms are connected to each other (it's a union-find / disjoint-set structure).
+type roomGroups map[*Room]*Room
+
+// find returns the room representing the group the given room is in.
+func (groups roomGroups) find(room *Room) *Room {
+	for groups[room] != nil && groups[room] != room {
+		room = groups[room]
+	}
+	return room
+}
+
+// union joins the groups the two rooms are in.
+func (groups roomGroups) union(a, b *Room) {
+	rootA, rootB := groups.find(a), groups.find(b)
+	if rootA != rootB {
+		groups[rootA] = rootB
+	}
 }
 
-func NewBSPRoom(x, y, w, h int) *BSPRoom {
-	return &BSPRoom{
-		X:           x,
-		Y:           y,
-		W:           w,
-		H:           h,
-		Connected:   []*BSPRoom{},
-		Traversable: true,
+// clampInt returns the value, clamped to be between min and max (inclusive).
+func clampInt(value, min, max int) int {
+	if value < min {
+		return min
+	} else if value > max {
+		return max
 	}
+	return value
 }
 
-// Area returns the area of the BSPRoom (width * height).
-func (bsp *BSPRoom) Area() int {
-	return bsp.W * bsp.H
+// minInt returns the smaller of the two values provided.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
 }
 
-// MinSize returns the minimum size of the room.
-func (bsp *BSPRoom) MinSize() int {
-	if bsp.W < bsp.H {
-		return bsp.W
+// absInt returns the absolute value of the integer provided.
+func absInt(value int) int {
+	if value < 0 {
+		return -value
 	}
-	return bsp.H
+	return value
 }
 
-func (bsp *BSPRoom) Center() Position {
-	return Position{bsp.X + bsp.W/2, bsp.Y + bsp.H/2}
+// maxInt returns the larger of the two values provided.
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
 }
 
-// CountHopsTo will count the number of hops to go from one room to another, by hopping through connected neighbors. If no traversable link between the two rooms found, CountHopsTo will return -1.
-func (bsp *BSPRoom) CountHopsTo(room *BSPRoom) int {
+// carveBSPRooms carves a room inside each of the leaves of the partition tree, and then connects sibling nodes with corridors,
+// working up from the bottom of the tree. The rooms and doors are added to the result provided.
+func (layout *Layout) carveBSPRooms(gen *generation, result *BSPResult, options BSPOptions) error {
+
+	// roomSpan returns a random start position and size for a room inside a partition spanning from start to start+size.
+	roomSpan := func(start, size int) (int, int) {
+
+		available := size - options.RoomPadding*2
+
+		if available < 1 {
+			return start + size/2, 1
+		}
+
+		roomSize := available - layout.RNG.Intn(available/2+1)
+
+		return start + options.RoomPadding + layout.RNG.Intn(available-roomSize+1), roomSize
 
-	toCheck := append([]*BSPRoom{}, bsp)
-	perRoomHopCount := map[*BSPRoom]int{
-		bsp: 0,
 	}
 
-	for len(toCheck) > 0 {
+	for _, leaf := range result.Tree.Leaves() {
+
+		x, w := roomSpan(leaf.X, leaf.W)
+		y, h := roomSpan(leaf.Y, leaf.H)
 
-		next := toCheck[0]
+		room := NewRoom(x, y, w, h)
 
-		if next == room {
-			return perRoomHopCount[next]
+		for cy := y; cy < y+h; cy++ {
+			for cx := x; cx < x+w; cx++ {
+				layout.Set(cx, cy, ' ')
+			}
 		}
 
-		toCheck = toCheck[1:]
+		leaf.Room = room
+		result.Rooms = append(result.Rooms, room)
 
-		if !next.Traversable {
-			continue
+		if err := gen.step(GenerationStep{Kind: StepRoom, X: x, Y: y, W: w, H: h, Room: room}); err != nil {
+			return err
+		}
+
+	}
+
+	var connect func(node *BSPNode) error
+
+	connect = func(node *BSPNode) error {
+
+		if node.IsLeaf() {
+			return nil
+		}
+
+		if err := connect(node.Left); err != nil {
+			return err
+		}
+
+		if err := connect(node.Right); err != nil {
+			return err
 		}
 
-		for _, connected := range next.Connected {
+		// Connect the two closest rooms on either side of the split.
+		var a, b *Room
 
-			if _, exists := perRoomHopCount[connected]; !exists {
-				toCheck = append(toCheck, connected)
-				perRoomHopCount[connected] = perRoomHopCount[next] + 1
+		for _, left := range node.Left.Rooms() {
+			for _, right := range node.Right.Rooms() {
+				if a == nil || left.Center().DistanceTo(right.Center()) < a.Center().DistanceTo(b.Center()) {
+					a, b = left, right
+				}
+			}
+		}
+
+		if err := layout.carveBSPCorridor(gen, result, a, b, options); err != nil {
+			return err
+		}
+
+		// Extra corridors make loops; they go between the next closest pair of rooms that aren't already connected.
+		if layout.RNG.Float32() < options.Doors.LoopChance {
+
+			var loopA, loopB *Room
+
+			for _, left := range node.Left.Rooms() {
+				for _, right := range node.Right.Rooms() {
+					if left == a && right == b {
+						continue
+					}
+					if loopA == nil || left.Center().DistanceTo(right.Center()) < loopA.Center().DistanceTo(loopB.Center()) {
+						loopA, loopB = left, right
+					}
+				}
+			}
+
+			if loopA != nil {
+				return layout.carveBSPCorridor(gen, result, loopA, loopB, options)
 			}
 
 		}
 
+		return nil
+
 	}
 
-	return -1
+	return connect(result.Tree)
+
 }
 
-// Disconnect removes the BSPRoom from any of its neighbors' Connected lists, breaking the link between them.
-func (bsp *BSPRoom) Disconnect() {
+// carveBSPCorridor carves an L-shaped corridor between the centers of the two rooms provided (see carveCorridor()), adding its doors
+// to the result.
+func (layout *Layout) carveBSPCorridor(gen *generation, result *BSPResult, a, b *Room, options BSPOptions) error {
 
-	for _, neighbor := range bsp.Connected {
-		for i, me := range neighbor.Connected {
-			if me == bsp {
-				neighbor.Connected = append(neighbor.Connected[:i], neighbor.Connected[i+1:]...)
-				break
-			}
+	from := a.Center()
+	to := b.Center()
+
+	corner := Position{to.X, from.Y}
+	if layout.RNG.Float32() >= 0.5 {
+		corner = Position{from.X, to.Y}
+	}
+
+	if err := layout.carveCorridor(gen, a, b, linePath(from, corner, to), options.WallValue, options.DoorValue); err != nil {
+		return err
+	}
+
+	result.Doors = append(result.Doors, a.Doors[len(a.Doors)-1], b.Doors[len(b.Doors)-1])
+
+	return nil
+
+}
+
+// linePath returns the cells along the straight (horizontal or vertical) lines joining each of the points provided in turn.
+func linePath(points ...Position) []Position {
+
+	path := []Position{}
+
+	for i := 0; i < len(points)-1; i++ {
+		from, to := points[i], points[i+1]
+		dx, dy := sign(to.X-from.X), sign(to.Y-from.Y)
+		for p := from; p != to; p = (Position{p.X + dx, p.Y + dy}) {
+			path = append(path, p)
 		}
 	}
 
-	bsp.Connected = []*BSPRoom{}
+	return append(path, points[len(points)-1])
+
 }
 
-// Necessary returns if the BSPRoom is necessary to facilitate traversal from its neighbors to the rest of the BSP Layout.
-func (bsp *BSPRoom) Necessary() bool {
+// carveCorridor carves a corridor through wallValue runes along the path provided, which leads from inside room a to inside room
+// b, placing doors of doorValue (if it isn't 0) where the corridor leaves the first room and enters the second, and connects them.
+func (layout *Layout) carveCorridor(gen *generation, a, b *Room, path []Position, wallValue, doorValue rune) error {
+
+	from := path[0]
+	to := path[len(path)-1]
+
+	// The doors go on the first cell outside of the first room and the last cell outside of the second room.
+	doorA, doorB := path[0], path[len(path)-1]
+
+	for _, cell := range path {
+		if !a.Contains(cell.X, cell.Y) {
+			doorA = cell
+			break
+		}
+	}
 
-	// If you only have one neighbor, then you're necessary
-	if len(bsp.Connected) == 1 {
-		return true
+	for i := len(path) - 1; i >= 0; i-- {
+		if !b.Contains(path[i].X, path[i].Y) {
+			doorB = path[i]
+			break
+		}
 	}
 
-	bsp.Traversable = false
+	// Door runes only go in walls, not in other rooms or corridors the path crosses.
+	doorRunes := []Position{}
 
-	for _, neighbor := range bsp.Connected {
+	for _, door := range []Position{doorA, doorB} {
+		if layout.Get(door.X, door.Y) == wallValue && (len(doorRunes) == 0 || doorRunes[0] != door) {
+			doorRunes = append(doorRunes, door)
+		}
+	}
 
-		// If your neighbor is only connected to you, then you're necessary.
-		if len(neighbor.Connected) <= 1 {
-			bsp.Traversable = true
-			return true
+	for _, cell := range path {
+		if layout.Get(cell.X, cell.Y) == wallValue {
+			layout.Set(cell.X, cell.Y, ' ')
 		}
+	}
 
-		for _, otherNeighbor := range bsp.Connected {
+	if err := gen.step(GenerationStep{Kind: StepCorridor, X: from.X, Y: from.Y, W: to.X - from.X, H: to.Y - from.Y}); err != nil {
+		return err
+	}
 
-			if otherNeighbor == bsp || otherNeighbor == neighbor {
-				continue
-			}
+	if doorValue != 0 {
+
+		for _, door := range doorRunes {
 
-			if neighbor.CountHopsTo(otherNeighbor) < 0 {
-				bsp.Traversable = true
-				return true
+			layout.Set(door.X, door.Y, doorValue)
+
+			if err := gen.step(GenerationStep{Kind: StepDoor, X: door.X, Y: door.Y, W: 1, H: 1}); err != nil {
+				return err
 			}
 
 		}
 
 	}
 
-	bsp.Traversable = true
-	return false
+	// The corridor has a doorway at each end, so each room gets its own door.
+	a.connect(b)
+	a.Doors = append(a.Doors, Door{X: doorA.X, Y: doorA.Y, From: a, To: b})
+	b.Doors = append(b.Doors, Door{X: doorB.X, Y: doorB.Y, From: b, To: a})
+
+	return nil
 
 }
+
+// sign returns -1 if the value is negative, 1 if it's positive, or 0 if it's 0.
+func sign(value int) int {
+	if value < 0 {
+		return -1
+	} else if value > 0 {
+		return 1
+	}
+	return 0
+}
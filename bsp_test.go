@@ -0,0 +1,218 @@
+package dngn
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// TestBSPDoorsPerWall checks that asking for more doors on each wall than there's room for places as many as fit, for every
+// placement.
+func TestBSPDoorsPerWall(t *testing.T) {
+
+	for _, placement := range []DoorPlacement{DoorPlacementRandom, DoorPlacementCentered, DoorPlacementCorners} {
+
+		layout := NewLayout(40, 30)
+		layout.RNG = rand.New(rand.NewSource(1))
+
+		options := NewDefaultBSPOptions()
+		options.Doors.DoorsPerWall = 50
+		options.Doors.Placement = placement
+
+		result, err := layout.GenerateBSP(options)
+		if err != nil {
+			t.Fatalf("placement %d: %v", placement, err)
+		}
+
+		if len(result.Doors) == 0 {
+			t.Errorf("placement %d: no doors were placed", placement)
+		}
+
+	}
+
+}
+
+// openRegions returns how many separate areas of cells other than the wall rune provided there are in the Layout.
+func openRegions(layout *Layout, wall rune) int {
+
+	regions := 0
+	seen := map[Position]bool{}
+
+	for y := 0; y < layout.Height; y++ {
+		for x := 0; x < layout.Width; x++ {
+
+			if seen[Position{x, y}] || layout.Get(x, y) == wall {
+				continue
+			}
+
+			regions++
+			seen[Position{x, y}] = true
+			toVisit := []Position{{x, y}}
+
+			for len(toVisit) > 0 {
+				cell := toVisit[0]
+				toVisit = toVisit[1:]
+				for _, next := range layout.Neighbors(cell.X, cell.Y, false) {
+					if layout.inBounds(next) && !seen[next] && layout.Get(next.X, next.Y) != wall {
+						seen[next] = true
+						toVisit = append(toVisit, next)
+					}
+				}
+			}
+
+		}
+	}
+
+	return regions
+
+}
+
+// TestBSPCarveRooms checks that carving rooms inside the partitions still leaves every room connected to every other, through one
+// open area, and that every room is a leaf of the partition tree.
+func TestBSPCarveRooms(t *testing.T) {
+
+	for seed := int64(1); seed <= 10; seed++ {
+
+		layout := NewLayout(60, 40)
+		layout.RNG = rand.New(rand.NewSource(seed))
+
+		options := NewDefaultBSPOptions()
+		options.CarveRooms = true
+
+		result, err := layout.GenerateBSP(options)
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+
+		if regions := openRegions(layout, options.WallValue); regions != 1 {
+			t.Errorf("seed %d: carved rooms are split into %d separate areas:\n%s", seed, regions, layout.DataToString())
+		}
+
+		if rooms := result.Tree.Rooms(); len(rooms) != len(result.Rooms) {
+			t.Errorf("seed %d: the tree has %d rooms, but %d were returned", seed, len(rooms), len(result.Rooms))
+		}
+
+	}
+
+}
+
+// TestBSPTreeAndDoors checks that the partition tree's children cover their parents, and that every door is on the map and listed
+// by the rooms on both sides of it.
+func TestBSPTreeAndDoors(t *testing.T) {
+
+	layout := NewLayout(60, 40)
+	layout.RNG = rand.New(rand.NewSource(1))
+
+	options := NewDefaultBSPOptions()
+
+	result, err := layout.GenerateBSP(options)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if tree := result.Tree; tree.X != 0 || tree.Y != 0 || tree.W != layout.Width || tree.H != layout.Height || tree.Parent != nil {
+		t.Errorf("the root of the tree doesn't cover the layout: %d, %d, %d, %d", tree.X, tree.Y, tree.W, tree.H)
+	}
+
+	var check func(node *BSPNode)
+	check = func(node *BSPNode) {
+
+		if node.IsLeaf() {
+			return
+		}
+
+		left, right := node.Left, node.Right
+
+		if left.Parent != node || right.Parent != node {
+			t.Errorf("children of %v don't point back to it", node)
+		}
+
+		if node.Vertical && (left.W+right.W != node.W || left.H != node.H || right.X != node.SplitPosition) {
+			t.Errorf("vertical split at %d doesn't divide %v into %v and %v", node.SplitPosition, *node, *left, *right)
+		}
+
+		if !node.Vertical && (left.H+right.H != node.H || left.W != node.W || right.Y != node.SplitPosition) {
+			t.Errorf("horizontal split at %d doesn't divide %v into %v and %v", node.SplitPosition, *node, *left, *right)
+		}
+
+		check(left)
+		check(right)
+
+	}
+
+	check(result.Tree)
+
+	for _, door := range result.Doors {
+
+		if layout.Get(door.X, door.Y) != options.DoorValue {
+			t.Errorf("door at %d, %d isn't on the map", door.X, door.Y)
+		}
+
+		found := false
+		for _, other := range door.To.Doors {
+			if other.X == door.X && other.Y == door.Y && other.To == door.From {
+				found = true
+			}
+		}
+
+		if !found {
+			t.Errorf("door at %d, %d isn't listed by the room it leads into", door.X, door.Y)
+		}
+
+	}
+
+	for _, room := range result.Rooms {
+		for _, door := range room.Doors {
+			if door.From != room {
+				t.Errorf("door at %d, %d listed by a room it doesn't lead out of", door.X, door.Y)
+			}
+		}
+	}
+
+}
+
+// TestBSPLoopChance checks that loop chances outside of 0 - 1 (including NaN) are rejected.
+func TestBSPLoopChance(t *testing.T) {
+
+	for _, chance := range []float32{float32(math.NaN()), -0.5, 1.5} {
+
+		layout := NewLayout(40, 30)
+		layout.RNG = rand.New(rand.NewSource(1))
+
+		options := NewDefaultBSPOptions()
+		options.Doors.LoopChance = chance
+
+		if _, err := layout.GenerateBSP(options); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("loop chance of %v: expected ErrInvalidArgument, got %v", chance, err)
+		}
+
+	}
+
+}
+
+// TestBSPEnsureConnected checks that EnsureConnected connects every room, even when border rooms are skipped and no loops are
+// added.
+func TestBSPEnsureConnected(t *testing.T) {
+
+	for seed := int64(1); seed <= 20; seed++ {
+
+		layout := NewLayout(60, 40)
+		layout.RNG = rand.New(rand.NewSource(seed))
+
+		options := NewDefaultBSPOptions()
+		options.Doors.SkipBorderRooms = true
+		options.Doors.LoopChance = 0
+
+		result, err := layout.GenerateBSP(options)
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+
+		if !result.Connected() {
+			t.Errorf("seed %d: not every room is connected", seed)
+		}
+
+	}
+
+}
@@ -0,0 +1,143 @@
+package dngn
+
+import (
+	"context"
+)
+
+// CellularOptions configures Layout.GenerateCellular().
+type CellularOptions struct {
+	FloorValue rune // The rune for open cave cells.
+	WallValue  rune // The rune for walls.
+
+	WallChance float32 // The chance (0 - 1) of each cell starting out as a wall, before the rules are run.
+	Iterations int     // How many times the rules are run over the whole Layout; more iterations make smoother caves.
+
+	// A floor cell turns into a wall if at least BirthLimit of its neighbors are walls, while a wall cell stays a wall if at least
+	// SurvivalLimit of its neighbors are. If either is 0, it depends on the Layout's Grid: on square grids, where each cell has 8
+	// neighbors (counting diagonals), BirthLimit is 5 and SurvivalLimit is 4; on hex grids, where each cell has 6, they're 4 and 3.
+	BirthLimit    int
+	SurvivalLimit int
+}
+
+// NewDefaultCellularOptions returns the default CellularOptions: caves of floors (' ') and walls ('x'), starting out 45% walls and
+// smoothed out over 5 iterations, with the birth and survival limits picked to suit the Layout's Grid.
+func NewDefaultCellularOptions() CellularOptions {
+	return CellularOptions{
+		FloorValue: ' ',
+		WallValue:  'x',
+		WallChance: 0.45,
+		Iterations: 5,
+	}
+}
+
+// validate returns an error if the options are invalid.
+func (options CellularOptions) validate() error {
+
+	// This is written so that NaN fails it too.
+	if !(options.WallChance >= 0 && options.WallChance <= 1) {
+		return invalidArgument("GenerateCellular", "wall chance must be between 0 and 1, got %v", options.WallChance)
+	}
+
+	if options.Iterations < 0 {
+		return invalidArgument("GenerateCellular", "iterations can't be negative, got %d", options.Iterations)
+	}
+
+	if options.BirthLimit < 0 || options.SurvivalLimit < 0 {
+		return invalidArgument("GenerateCellular", "birth and survival limits can't be negative, got %d and %d", options.BirthLimit, options.SurvivalLimit)
+	}
+
+	return nil
+
+}
+
+// GenerateCellular generates caves using cellular automata. Each cell starts out as a wall or floor at random, and then the rules
+// (see CellularOptions) are run over every cell at once, again and again, which clumps walls together and opens up the space between
+// them into caves. Cells along the edges of the Layout are always walls. Neighbors follow the Layout's Grid (see Layout.Neighbors()),
+// so on hex grids, the caves grow from hex to hex.
+// Link: http://www.roguebasin.com/index.php?title=Cellular_Automata_Method_for_Generating_Random_Cave-Like_Levels
+// It returns a RoomGraph containing a Room for each separate cave, or an error if the options are invalid.
+// GenerateCellular is the same as GenerateCellularContext() with a background context.
+func (layout *Layout) GenerateCellular(options CellularOptions) (*RoomGraph, error) {
+	return layout.GenerateCellularContext(context.Background(), options)
+}
+
+// GenerateCellularContext works like GenerateCellular(), but stops and returns an error if the context is cancelled or the Layout's
+// StepBudget runs out before generation finishes.
+func (layout *Layout) GenerateCellularContext(ctx context.Context, options CellularOptions) (*RoomGraph, error) {
+
+	if err := layout.validateSize("GenerateCellular"); err != nil {
+		return nil, err
+	}
+
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+
+	birthLimit, survivalLimit := 5, 4
+	if layout.Grid.IsHex() {
+		birthLimit, survivalLimit = 4, 3
+	}
+	if options.BirthLimit > 0 {
+		birthLimit = options.BirthLimit
+	}
+	if options.SurvivalLimit > 0 {
+		survivalLimit = options.SurvivalLimit
+	}
+
+	gen := layout.generation(ctx, "cellular")
+
+	edge := func(x, y int) bool {
+		return x == 0 || y == 0 || x == layout.Width-1 || y == layout.Height-1
+	}
+
+	for y := 0; y < layout.Height; y++ {
+		for x := 0; x < layout.Width; x++ {
+			if edge(x, y) || layout.RNG.Float32() < options.WallChance {
+				layout.Set(x, y, options.WallValue)
+			} else {
+				layout.Set(x, y, options.FloorValue)
+			}
+		}
+	}
+
+	if err := gen.step(GenerationStep{Kind: StepFill, X: 0, Y: 0, W: layout.Width, H: layout.Height}); err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < options.Iterations; i++ {
+
+		// The rules are run on a copy, so that every cell changes at once.
+		previous := layout.Clone()
+
+		for y := 0; y < layout.Height; y++ {
+			for x := 0; x < layout.Width; x++ {
+
+				if edge(x, y) {
+					continue
+				}
+
+				walls := 0
+				for _, neighbor := range layout.Neighbors(x, y, true) {
+					if previous.Get(neighbor.X, neighbor.Y) == options.WallValue {
+						walls++
+					}
+				}
+
+				if (previous.Get(x, y) == options.WallValue && walls >= survivalLimit) || walls >= birthLimit {
+					layout.Set(x, y, options.WallValue)
+				} else {
+					layout.Set(x, y, options.FloorValue)
+				}
+
+			}
+		}
+
+		if err := gen.step(GenerationStep{Kind: StepFill, X: 0, Y: 0, W: layout.Width, H: layout.Height}); err != nil {
+			return nil, err
+		}
+
+	}
+
+	return layout.regionGraph(options.FloorValue), nil
+
+}
@@ -0,0 +1,56 @@
+package dngn
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// TestCellular checks that the same seed grows the same caves on square and hex grids, with walls all around the edges.
+func TestCellular(t *testing.T) {
+
+	generate := func(grid GridType, seed int64) *Layout {
+
+		layout := NewLayout(60, 40)
+		layout.Grid = grid
+		layout.RNG = rand.New(rand.NewSource(seed))
+
+		if _, err := layout.GenerateCellular(NewDefaultCellularOptions()); err != nil {
+			t.Fatalf("%s, seed %d: %v", grid, seed, err)
+		}
+
+		return layout
+
+	}
+
+	for _, grid := range []GridType{GridSquare, GridHexPointyOdd, GridHexFlatEven} {
+
+		for seed := int64(1); seed <= 3; seed++ {
+
+			first := generate(grid, seed)
+
+			if first.DataToString() != generate(grid, seed).DataToString() {
+				t.Errorf("%s, seed %d: the same seed grew different caves", grid, seed)
+			}
+
+			for x := 0; x < first.Width; x++ {
+				for y := 0; y < first.Height; y++ {
+					if (x == 0 || y == 0 || x == first.Width-1 || y == first.Height-1) && first.Get(x, y) != 'x' {
+						t.Errorf("%s, seed %d: edge cell %d, %d isn't a wall", grid, seed, x, y)
+					}
+				}
+			}
+
+		}
+
+	}
+
+	options := NewDefaultCellularOptions()
+	options.WallChance = float32(math.NaN())
+
+	if _, err := NewLayout(20, 20).GenerateCellular(options); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument with a NaN wall chance, got %v", err)
+	}
+
+}
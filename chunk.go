@@ -0,0 +1,433 @@
+package dngn
+
+import (
+	"context"
+	"strings"
+)
+
+// ChunkOpening is a set of sides of a chunk that are open, so that the chunk connects to its neighbors on those sides. Sides are
+// combined with |, like ChunkLeft | ChunkRight.
+type ChunkOpening int
+
+const (
+	ChunkLeft ChunkOpening = 1 << iota
+	ChunkRight
+	ChunkTop
+	ChunkBottom
+)
+
+// String returns the sides in the set as letters, in the order left, right, top, bottom (for example, "LRB"), or "-" if no sides
+// are open.
+func (opening ChunkOpening) String() string {
+
+	sides := strings.Builder{}
+
+	for i, letter := range "LRTB" {
+		if opening&(1<<i) != 0 {
+			sides.WriteRune(letter)
+		}
+	}
+
+	if sides.Len() == 0 {
+		return "-"
+	}
+
+	return sides.String()
+
+}
+
+// Has returns if all of the sides in other are also in the set.
+func (opening ChunkOpening) Has(other ChunkOpening) bool {
+	return opening&other == other
+}
+
+// mirrored returns the set with the left and right sides swapped.
+func (opening ChunkOpening) mirrored() ChunkOpening {
+	mirrored := opening &^ (ChunkLeft | ChunkRight)
+	if opening&ChunkLeft != 0 {
+		mirrored |= ChunkRight
+	}
+	if opening&ChunkRight != 0 {
+		mirrored |= ChunkLeft
+	}
+	return mirrored
+}
+
+// The runes with special meanings in a ChunkTemplate's Rows. These follow the notation used for Spelunky's room templates.
+const (
+	ChunkTemplateFloor  = '0' // Drawn as ChunkOptions.FloorValue.
+	ChunkTemplateWall   = '1' // Drawn as ChunkOptions.WallValue.
+	ChunkTemplateRandom = '2' // Drawn as ChunkOptions.WallValue with a chance of ChunkOptions.RandomChance, or FloorValue otherwise.
+)
+
+// ChunkTemplate is a hand-made piece of a level that Layout.GenerateChunks() fills chunks with. Rows are the template's rows of
+// cells, from top to bottom; ChunkTemplateFloor, ChunkTemplateWall, and ChunkTemplateRandom are drawn as floor, wall, or either,
+// while any other rune (a ladder or spikes, say) is copied as it is. Openings are the sides of the template that are open.
+// To make sure that neighboring chunks actually connect, a template that's open on a side should leave the middle of that side
+// open, with floor cells that are reachable from the template's other openings; every template open on a side should leave the
+// same cells open, so they line up. The default templates, for example, leave the middle four cells of their top and bottom sides
+// open, and the middle five cells of their left and right sides (rows 2 to 6).
+type ChunkTemplate struct {
+	Openings ChunkOpening
+	Rows     []string
+}
+
+// size returns the width and height of the template.
+func (template ChunkTemplate) size() (int, int) {
+	width := 0
+	if len(template.Rows) > 0 {
+		width = len([]rune(template.Rows[0]))
+	}
+	return width, len(template.Rows)
+}
+
+// NewDefaultChunkTemplates returns a small library of 10x8 platformer templates, covering every set of openings that the path
+// through a level can need (LR, LRB, LRT, and LRTB), as well as a closed side room.
+func NewDefaultChunkTemplates() []ChunkTemplate {
+	return []ChunkTemplate{
+		{Openings: ChunkLeft | ChunkRight, Rows: []string{
+			"1111111111",
+			"1122222211",
+			"0000000000",
+			"0000000000",
+			"0000000000",
+			"0001111000",
+			"0000000000",
+			"1111111111",
+		}},
+		{Openings: ChunkLeft | ChunkRight, Rows: []string{
+			"2222222222",
+			"0000000000",
+			"0000000000",
+			"0000000000",
+			"0022002200",
+			"0000000000",
+			"0000000000",
+			"1111111111",
+		}},
+		{Openings: ChunkLeft | ChunkRight | ChunkBottom, Rows: []string{
+			"1111111111",
+			"0000000000",
+			"0000000000",
+			"0000000000",
+			"0220000220",
+			"0000000000",
+			"0000000000",
+			"1110000111",
+		}},
+		{Openings: ChunkLeft | ChunkRight | ChunkTop, Rows: []string{
+			"1110000111",
+			"1100000011",
+			"0000000000",
+			"0000000000",
+			"0000110000",
+			"0000000000",
+			"0000000000",
+			"1111111111",
+		}},
+		{Openings: ChunkLeft | ChunkRight | ChunkTop | ChunkBottom, Rows: []string{
+			"1110000111",
+			"0000000000",
+			"0000000000",
+			"0000000000",
+			"0110000220",
+			"0000000000",
+			"0000000000",
+			"1110000111",
+		}},
+		{Openings: 0, Rows: []string{
+			"1111111111",
+			"1111111111",
+			"1100000011",
+			"1100000011",
+			"1122222211",
+			"1111111111",
+			"1111111111",
+			"1111111111",
+		}},
+	}
+}
+
+// ChunkOptions configures Layout.GenerateChunks().
+type ChunkOptions struct {
+	FloorValue rune // The rune for ChunkTemplateFloor cells.
+	WallValue  rune // The rune for ChunkTemplateWall cells, as well as the border and any space left over around the grid.
+
+	Templates    []ChunkTemplate // The templates to fill chunks with. They all need to be the same size, which sets the size of the chunks.
+	RandomChance float32         // The chance (0 - 1) of each ChunkTemplateRandom cell being a wall.
+	Mirror       bool            // If templates can be mirrored horizontally (with their left and right openings swapped) for more variety.
+
+	DropChance float32 // The chance (0 - 1) of the path dropping down a row at each step, rather than carrying on sideways.
+	Border     int     // How thick the wall around the grid of chunks is.
+}
+
+// NewDefaultChunkOptions returns the default ChunkOptions: the templates from NewDefaultChunkTemplates() drawn with floors (' ') and
+// walls ('x'), mirrored at random, inside of a one-cell border.
+func NewDefaultChunkOptions() ChunkOptions {
+	return ChunkOptions{
+		FloorValue:   ' ',
+		WallValue:    'x',
+		Templates:    NewDefaultChunkTemplates(),
+		RandomChance: 0.5,
+		Mirror:       true,
+		DropChance:   0.25,
+		Border:       1,
+	}
+}
+
+// validate returns an error if the options can't be used to generate chunks in the Layout provided.
+func (options ChunkOptions) validate(layout *Layout) error {
+
+	if len(options.Templates) == 0 {
+		return invalidArgument("GenerateChunks", "no templates given")
+	}
+
+	width, height := options.Templates[0].size()
+
+	if width == 0 || height == 0 {
+		return invalidArgument("GenerateChunks", "templates can't be empty")
+	}
+
+	for i, template := range options.Templates {
+		if len(template.Rows) != height {
+			return invalidArgument("GenerateChunks", "template %d is %d rows tall, but the first template is %d rows tall", i, len(template.Rows), height)
+		}
+		for _, row := range template.Rows {
+			if len([]rune(row)) != width {
+				return invalidArgument("GenerateChunks", "template %d has a row that's %d cells wide, but the first template is %d cells wide", i, len([]rune(row)), width)
+			}
+		}
+	}
+
+	// These are written so that NaN fails them too.
+	if !(options.RandomChance >= 0 && options.RandomChance <= 1) {
+		return invalidArgument("GenerateChunks", "random chance must be between 0 and 1, got %v", options.RandomChance)
+	}
+
+	if !(options.DropChance >= 0 && options.DropChance <= 1) {
+		return invalidArgument("GenerateChunks", "drop chance must be between 0 and 1, got %v", options.DropChance)
+	}
+
+	if options.Border < 0 {
+		return invalidArgument("GenerateChunks", "border can't be negative, got %d", options.Border)
+	}
+
+	if layout.Width-options.Border*2 < width || layout.Height-options.Border*2 < height {
+		return invalidArgument("GenerateChunks", "a %dx%d layout is too small for a single %dx%d chunk inside of a border of %d", layout.Width, layout.Height, width, height, options.Border)
+	}
+
+	return nil
+
+}
+
+// ChunkSlot is one of the chunks in the grid laid out by Layout.GenerateChunks(). Column and Row are the slot's position in the grid,
+// while X, Y, W, and H are its bounds in the Layout. Openings are the sides the path through the level needs the slot to be open on
+// (which are none for slots off of the path), and Template is the template the slot was filled with (mirrored if Mirrored is true),
+// which is open on at least those sides.
+type ChunkSlot struct {
+	Column, Row int
+	X, Y, W, H  int
+	OnPath      bool
+	Openings    ChunkOpening
+	Template    *ChunkTemplate
+	Mirrored    bool
+}
+
+// ChunkResult is the result of generating a level through Layout.GenerateChunks(). Slots are every slot in the grid, row by row
+// from the top left; Path is the slots along the path through the level, from the entrance (in the top row) to the exit (in the
+// bottom row).
+type ChunkResult struct {
+	Columns, Rows int
+	Slots         []*ChunkSlot
+	Path          []*ChunkSlot
+}
+
+// Slot returns the slot at the column and row provided, or nil if the position is outside of the grid.
+func (result *ChunkResult) Slot(column, row int) *ChunkSlot {
+	if column < 0 || row < 0 || column >= result.Columns || row >= result.Rows {
+		return nil
+	}
+	return result.Slots[row*result.Columns+column]
+}
+
+// Entrance returns the first slot on the path through the level.
+func (result *ChunkResult) Entrance() *ChunkSlot {
+	return result.Path[0]
+}
+
+// Exit returns the last slot on the path through the level.
+func (result *ChunkResult) Exit() *ChunkSlot {
+	return result.Path[len(result.Path)-1]
+}
+
+// GenerateChunks generates a level the way Spelunky does: the Layout is split up into a coarse grid of chunk slots, as many as fit
+// inside of the border, and a path is walked from a random slot in the top row down to the bottom row, wandering left and right and
+// dropping down a row every so often. Each slot on the path is classified by the sides it needs to be open on to carry the path
+// along (for example, LR for a slot the path goes straight through, or LRB for one it drops out of), and then every slot is filled
+// with a random template that's open on at least those sides, so the level can always be crossed from the entrance to the exit.
+// Slots off of the path can be filled with any template. ChunkTemplateRandom cells and mirroring add more variety.
+// It returns the generated level, or an error if the options are invalid (see ChunkOptions) or no template fits one of the slots on
+// the path.
+// GenerateChunks is the same as GenerateChunksContext() with a background context.
+func (layout *Layout) GenerateChunks(options ChunkOptions) (*ChunkResult, error) {
+	return layout.GenerateChunksContext(context.Background(), options)
+}
+
+// GenerateChunksContext works like GenerateChunks(), but stops and returns an error if the context is cancelled or the Layout's
+// StepBudget runs out before generation finishes.
+func (layout *Layout) GenerateChunksContext(ctx context.Context, options ChunkOptions) (*ChunkResult, error) {
+
+	if err := layout.validateSize("GenerateChunks"); err != nil {
+		return nil, err
+	}
+
+	if err := options.validate(layout); err != nil {
+		return nil, err
+	}
+
+	layout.Select().Fill(options.WallValue)
+
+	gen := layout.generation(ctx, "chunks")
+
+	chunkW, chunkH := options.Templates[0].size()
+
+	result := &ChunkResult{
+		Columns: (layout.Width - options.Border*2) / chunkW,
+		Rows:    (layout.Height - options.Border*2) / chunkH,
+		Slots:   []*ChunkSlot{},
+		Path:    []*ChunkSlot{},
+	}
+
+	// The grid is centered in the space inside of the border.
+	offsetX := options.Border + (layout.Width-options.Border*2-result.Columns*chunkW)/2
+	offsetY := options.Border + (layout.Height-options.Border*2-result.Rows*chunkH)/2
+
+	for row := 0; row < result.Rows; row++ {
+		for column := 0; column < result.Columns; column++ {
+			result.Slots = append(result.Slots, &ChunkSlot{
+				Column: column,
+				Row:    row,
+				X:      offsetX + column*chunkW,
+				Y:      offsetY + row*chunkH,
+				W:      chunkW,
+				H:      chunkH,
+			})
+		}
+	}
+
+	// Walk the path. In each row, the path heads either left or right, and keeps going that way until it drops down or runs into
+	// the side of the grid; this way, it never doubles back on itself.
+	current := result.Slot(layout.RNG.Intn(result.Columns), 0)
+	current.OnPath = true
+	result.Path = append(result.Path, current)
+
+	direction := 1
+	if layout.RNG.Intn(2) == 0 {
+		direction = -1
+	}
+
+	for {
+
+		if err := gen.step(GenerationStep{Kind: StepWalk, X: current.X, Y: current.Y, W: current.W, H: current.H}); err != nil {
+			return nil, err
+		}
+
+		next := result.Slot(current.Column+direction, current.Row)
+
+		if next == nil || layout.RNG.Float32() < options.DropChance {
+
+			next = result.Slot(current.Column, current.Row+1)
+
+			if next == nil {
+				break
+			}
+
+			current.Openings |= ChunkBottom
+			next.Openings |= ChunkTop
+
+			// After dropping down, the path heads off either way again, unless one way is blocked by the side of the grid.
+			direction = 1
+			if next.Column == result.Columns-1 || (next.Column > 0 && layout.RNG.Intn(2) == 0) {
+				direction = -1
+			}
+
+		} else if direction > 0 {
+			current.Openings |= ChunkRight
+			next.Openings |= ChunkLeft
+		} else {
+			current.Openings |= ChunkLeft
+			next.Openings |= ChunkRight
+		}
+
+		next.OnPath = true
+		result.Path = append(result.Path, next)
+		current = next
+
+	}
+
+	// Fill every slot with a template that fits it.
+	type candidate struct {
+		template *ChunkTemplate
+		mirrored bool
+	}
+
+	for _, slot := range result.Slots {
+
+		candidates := []candidate{}
+
+		for i := range options.Templates {
+			template := &options.Templates[i]
+			if template.Openings.Has(slot.Openings) {
+				candidates = append(candidates, candidate{template, false})
+			}
+			if options.Mirror && template.Openings.mirrored().Has(slot.Openings) {
+				candidates = append(candidates, candidate{template, true})
+			}
+		}
+
+		if len(candidates) == 0 {
+			return nil, invalidArgument("GenerateChunks", "no template is open on the sides %s", slot.Openings)
+		}
+
+		chosen := candidates[layout.RNG.Intn(len(candidates))]
+		slot.Template = chosen.template
+		slot.Mirrored = chosen.mirrored
+
+		for y, row := range chosen.template.Rows {
+
+			cells := []rune(row)
+
+			for x := range cells {
+
+				value := cells[x]
+				if chosen.mirrored {
+					value = cells[len(cells)-1-x]
+				}
+
+				switch value {
+				case ChunkTemplateFloor:
+					value = options.FloorValue
+				case ChunkTemplateWall:
+					value = options.WallValue
+				case ChunkTemplateRandom:
+					value = options.FloorValue
+					if layout.RNG.Float32() < options.RandomChance {
+						value = options.WallValue
+					}
+				}
+
+				layout.Set(slot.X+x, slot.Y+y, value)
+
+			}
+
+		}
+
+		if err := gen.step(GenerationStep{Kind: StepFill, X: slot.X, Y: slot.Y, W: slot.W, H: slot.H}); err != nil {
+			return nil, err
+		}
+
+	}
+
+	return result, nil
+
+}
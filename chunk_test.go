@@ -0,0 +1,128 @@
+package dngn
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// TestGenerateChunks checks that neighboring slots along the path are open towards each other, that their openings line up (with
+// floor on both sides of the edge between them), and that the exit can be reached from the entrance.
+func TestGenerateChunks(t *testing.T) {
+
+	for seed := int64(1); seed <= 10; seed++ {
+
+		layout := NewLayout(62, 42)
+		layout.RNG = rand.New(rand.NewSource(seed))
+
+		options := NewDefaultChunkOptions()
+
+		result, err := layout.GenerateChunks(options)
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+
+		if result.Entrance().Row != 0 || result.Exit().Row != result.Rows-1 {
+			t.Errorf("seed %d: the path goes from row %d to row %d", seed, result.Entrance().Row, result.Exit().Row)
+		}
+
+		for i := 1; i < len(result.Path); i++ {
+
+			a, b := result.Path[i-1], result.Path[i]
+
+			// The sides of a and b facing each other, and the cells on each side of the edge between them.
+			var side, other ChunkOpening
+			var edge [][2]Position
+
+			switch {
+			case b.Row == a.Row+1 && b.Column == a.Column:
+				side, other = ChunkBottom, ChunkTop
+				for x := 0; x < a.W; x++ {
+					edge = append(edge, [2]Position{{a.X + x, a.Y + a.H - 1}, {b.X + x, b.Y}})
+				}
+			case b.Row == a.Row && b.Column == a.Column+1:
+				side, other = ChunkRight, ChunkLeft
+				for y := 0; y < a.H; y++ {
+					edge = append(edge, [2]Position{{a.X + a.W - 1, a.Y + y}, {b.X, b.Y + y}})
+				}
+			case b.Row == a.Row && b.Column == a.Column-1:
+				side, other = ChunkLeft, ChunkRight
+				for y := 0; y < a.H; y++ {
+					edge = append(edge, [2]Position{{a.X, a.Y + y}, {b.X + b.W - 1, b.Y + y}})
+				}
+			default:
+				t.Fatalf("seed %d: path slots %d, %d and %d, %d aren't next to each other", seed, a.Column, a.Row, b.Column, b.Row)
+			}
+
+			for _, slot := range []*ChunkSlot{a, b} {
+				opening := slot.Template.Openings
+				if slot.Mirrored {
+					opening = opening.mirrored()
+				}
+				if !opening.Has(slot.Openings) {
+					t.Errorf("seed %d: slot %d, %d needs %s, but its template is open on %s", seed, slot.Column, slot.Row, slot.Openings, opening)
+				}
+			}
+
+			if !a.Openings.Has(side) || !b.Openings.Has(other) {
+				t.Errorf("seed %d: slots %d, %d (%s) and %d, %d (%s) aren't open towards each other", seed, a.Column, a.Row, a.Openings, b.Column, b.Row, b.Openings)
+			}
+
+			lined := false
+			for _, cells := range edge {
+				if layout.Get(cells[0].X, cells[0].Y) == options.FloorValue && layout.Get(cells[1].X, cells[1].Y) == options.FloorValue {
+					lined = true
+					break
+				}
+			}
+
+			if !lined {
+				t.Errorf("seed %d: the openings between slots %d, %d and %d, %d don't line up", seed, a.Column, a.Row, b.Column, b.Row)
+			}
+
+		}
+
+		floorIn := func(slot *ChunkSlot) Position {
+			for y := slot.Y; y < slot.Y+slot.H; y++ {
+				for x := slot.X; x < slot.X+slot.W; x++ {
+					if layout.Get(x, y) == options.FloorValue {
+						return Position{x, y}
+					}
+				}
+			}
+			t.Fatalf("seed %d: slot %d, %d has no floor", seed, slot.Column, slot.Row)
+			return Position{}
+		}
+
+		entrance, exit := floorIn(result.Entrance()), floorIn(result.Exit())
+
+		if reachable := layout.SelectContiguous(entrance.X, entrance.Y, false); !reachable.Contains(exit.X, exit.Y) {
+			t.Errorf("seed %d: the exit can't be reached from the entrance", seed)
+		}
+
+	}
+
+}
+
+// TestGenerateChunksInvalid checks that chances outside of 0 - 1 (including NaN) are rejected.
+func TestGenerateChunksInvalid(t *testing.T) {
+
+	nan := float32(math.NaN())
+
+	for name, set := range map[string]func(options *ChunkOptions){
+		"random chance of NaN": func(options *ChunkOptions) { options.RandomChance = nan },
+		"drop chance of NaN":   func(options *ChunkOptions) { options.DropChance = nan },
+		"drop chance of -1":    func(options *ChunkOptions) { options.DropChance = -1 },
+	} {
+
+		options := NewDefaultChunkOptions()
+		set(&options)
+
+		if _, err := NewLayout(62, 42).GenerateChunks(options); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("%s: expected ErrInvalidArgument, got %v", name, err)
+		}
+
+	}
+
+}
@@ -0,0 +1,571 @@
+/*
+Command dngn runs one of dngn's map generators from the command line and writes the result out as ASCII, JSON, or PNG. It's
+useful for iterating on generation parameters and seeds without having to write (or compile) any Go.
+
+Usage:
+
+	dngn [flags]
+
+For example, to generate an 80x45 BSP map with 30 splits using seed 42 and save it as an image:
+
+	dngn -gen bsp -width 80 -height 45 -seed 42 -splits 30 -o map.png
+
+Multi-step generation recipes (see dngn.Pipeline) saved as JSON can be run with the -recipe flag:
+
+	dngn -recipe cave.json -seed 7 -format json
+
+Run dngn -list to see the available generators, or dngn -help to see every flag.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/solarlune/dngn"
+)
+
+// options holds every generator-related flag. Each generator only reads the flags that apply to it.
+type options struct {
+	Floor, Wall, Door rune
+
+	// BSP
+	Splits      int
+	MinRoomSize int
+	Carve       bool
+	Padding     int
+	Doors       int
+	DoorWidth   int
+	Loops       float64
+	Placement   string
+	SkipBorder  bool
+	Connected   bool
+
+	// Random rooms
+	Rooms               int
+	MinWidth, MinHeight int
+	MaxWidth, MaxHeight int
+	Connect             bool
+
+	// Drunk walk
+	Fill             float64
+	Walkers          int
+	MaxWalkers       int
+	Momentum         float64
+	BiasX, BiasY     float64
+	Spawn, Death     float64
+	Steps            int
+	RestartFromFloor bool
+	Brush            int
+	Border           int
+
+	// Cyclic
+	CellSize  int
+	SubCycles int
+	Shortcuts int
+	Valves    int
+
+	// DLA
+	Particles  string
+	Attraction float64
+	MirrorX    bool
+	MirrorY    bool
+
+	// Noise
+	Noise     string
+	Frequency float64
+	Octaves   int
+	Ridged    bool
+	Warp      float64
+	Rivers    int
+	Roads     int
+
+	// Town
+	StreetWidth int
+	BlockSize   int
+	LotSize     int
+	Buildings   float64
+	NoPlaza     bool
+
+	// Chunks
+	Drop      float64
+	NoMirror  bool
+	Reachable bool
+
+	// Cellular
+	WallChance float64
+	Iterations int
+}
+
+// generator is a named map generator that can be run from the command line.
+type generator struct {
+	Description string
+	Run         func(layout *dngn.Layout, opt options) error
+}
+
+// generators are the generators available through the -gen flag. To expose a new generator through the CLI, add it here (and
+// any flags it needs to the options struct).
+var generators = map[string]generator{
+
+	"bsp": {
+		Description: "Binary space partitioning (uses -splits, -minroom, -carve, -padding, -doors, -doorwidth, -loops, -placement, -skipborder, -connected, -wall, -door)",
+		Run: func(layout *dngn.Layout, opt options) error {
+			bspOptions := dngn.NewDefaultBSPOptions()
+			bspOptions.WallValue = opt.Wall
+			bspOptions.DoorValue = opt.Door
+			bspOptions.SplitCount = opt.Splits
+			bspOptions.MinimumRoomSize = opt.MinRoomSize
+			bspOptions.CarveRooms = opt.Carve
+			bspOptions.RoomPadding = opt.Padding
+			bspOptions.Doors.DoorsPerWall = opt.Doors
+			bspOptions.Doors.DoorWidth = opt.DoorWidth
+			bspOptions.Doors.LoopChance = float32(opt.Loops)
+			bspOptions.Doors.SkipBorderRooms = opt.SkipBorder
+			bspOptions.EnsureConnected = opt.Connected
+			switch opt.Placement {
+			case "random":
+				bspOptions.Doors.Placement = dngn.DoorPlacementRandom
+			case "centered":
+				bspOptions.Doors.Placement = dngn.DoorPlacementCentered
+			case "corners":
+				bspOptions.Doors.Placement = dngn.DoorPlacementCorners
+			default:
+				return fmt.Errorf("unknown door placement %q; expected random, centered, or corners", opt.Placement)
+			}
+			_, err := layout.GenerateBSP(bspOptions)
+			return err
+		},
+	},
+
+	"rooms": {
+		Description: "Randomly placed rooms (uses -rooms, -minw, -minh, -maxw, -maxh, -connect, -floor, -wall)",
+		Run: func(layout *dngn.Layout, opt options) error {
+			_, err := layout.GenerateRandomRooms(opt.Floor, opt.Wall, opt.Rooms, opt.MinWidth, opt.MinHeight, opt.MaxWidth, opt.MaxHeight, opt.Connect)
+			return err
+		},
+	},
+
+	"drunk": {
+		Description: "Drunk walk caves (uses -fill, -walkers, -maxwalkers, -momentum, -biasx, -biasy, -spawn, -death, -steps, -restart, -brush, -border, -floor, -wall)",
+		Run: func(layout *dngn.Layout, opt options) error {
+			drunkOptions := dngn.NewDefaultDrunkWalkOptions()
+			drunkOptions.FloorValue = opt.Floor
+			drunkOptions.WallValue = opt.Wall
+			drunkOptions.PercentageFilled = float32(opt.Fill)
+			drunkOptions.Walkers = opt.Walkers
+			drunkOptions.MaxWalkers = opt.MaxWalkers
+			drunkOptions.Momentum = float32(opt.Momentum)
+			drunkOptions.BiasX = float32(opt.BiasX)
+			drunkOptions.BiasY = float32(opt.BiasY)
+			drunkOptions.SpawnChance = float32(opt.Spawn)
+			drunkOptions.DeathChance = float32(opt.Death)
+			drunkOptions.MaxSteps = opt.Steps
+			drunkOptions.RestartFromFloor = opt.RestartFromFloor
+			drunkOptions.BrushSize = opt.Brush
+			drunkOptions.Padding = opt.Border
+			_, err := layout.GenerateDrunkWalkWithOptions(drunkOptions)
+			return err
+		},
+	},
+
+	"dla": {
+		Description: "Diffusion-limited aggregation caverns (uses -fill, -particles, -attract, -mirrorx, -mirrory, -border, -floor, -wall)",
+		Run: func(layout *dngn.Layout, opt options) error {
+			dlaOptions := dngn.NewDefaultDLAOptions()
+			dlaOptions.FloorValue = opt.Floor
+			dlaOptions.WallValue = opt.Wall
+			dlaOptions.PercentageFilled = float32(opt.Fill)
+			dlaOptions.Attraction = float32(opt.Attraction)
+			dlaOptions.MirrorX = opt.MirrorX
+			dlaOptions.MirrorY = opt.MirrorY
+			dlaOptions.Padding = opt.Border
+			switch opt.Particles {
+			case "edges":
+				dlaOptions.Spawn = dngn.DLASpawnEdges
+			case "anywhere":
+				dlaOptions.Spawn = dngn.DLASpawnAnywhere
+			default:
+				return fmt.Errorf("unknown particle spawn %q; expected edges or anywhere", opt.Particles)
+			}
+			_, err := layout.GenerateDLA(dlaOptions)
+			return err
+		},
+	},
+
+	"noise": {
+		Description: "Overworld terrain from noise: water, sand, grass, and mountains (uses -noise, -freq, -octaves, -ridged, -warp, -rivers, -roads)",
+		Run: func(layout *dngn.Layout, opt options) error {
+			noiseType, err := parseNoiseType(opt.Noise)
+			if err != nil {
+				return err
+			}
+			noise := dngn.NewNoise(noiseType, layout.RNG.Int63())
+			noise.Frequency = opt.Frequency
+			noise.Octaves = opt.Octaves
+			noise.Ridged = opt.Ridged
+			noise.WarpStrength = opt.Warp
+			if _, err = layout.GenerateFromNoise([]*dngn.Noise{noise}, dngn.NewDefaultNoiseBands()); err != nil {
+				return err
+			}
+			return carveRiversAndRoads(layout, noise.HeightField(), opt.Rivers, opt.Roads)
+		},
+	},
+
+	"biomes": {
+		Description: "Overworld biomes from elevation, temperature (by latitude), and moisture (uses -noise, -freq, -octaves, -ridged, -warp)",
+		Run: func(layout *dngn.Layout, opt options) error {
+			noiseType, err := parseNoiseType(opt.Noise)
+			if err != nil {
+				return err
+			}
+			elevation := dngn.NewNoise(noiseType, layout.RNG.Int63())
+			elevation.Frequency = opt.Frequency
+			elevation.Octaves = opt.Octaves
+			elevation.Ridged = opt.Ridged
+			elevation.WarpStrength = opt.Warp
+			moisture := dngn.NewNoise(noiseType, layout.RNG.Int63())
+			moisture.Frequency = opt.Frequency
+			moisture.Octaves = opt.Octaves
+			_, err = layout.GenerateBiomes(dngn.BiomeFields{Elevation: elevation, Moisture: moisture}, dngn.NewDefaultBiomes())
+			return err
+		},
+	},
+
+	"town": {
+		Description: "Towns with streets, lots, buildings, and a plaza (uses -street, -block, -lot, -buildings, -noplaza, -floor, -wall, -door)",
+		Run: func(layout *dngn.Layout, opt options) error {
+			townOptions := dngn.NewDefaultTownOptions()
+			townOptions.FloorValue = opt.Floor
+			townOptions.WallValue = opt.Wall
+			townOptions.DoorValue = opt.Door
+			townOptions.StreetWidth = opt.StreetWidth
+			townOptions.BlockSize = opt.BlockSize
+			townOptions.LotSize = opt.LotSize
+			townOptions.BuildingChance = float32(opt.Buildings)
+			townOptions.Plaza = !opt.NoPlaza
+			_, err := layout.GenerateTown(townOptions)
+			return err
+		},
+	},
+
+	"chunks": {
+		Description: "Spelunky-style platformer levels built from templates along a path from top to bottom (uses -drop, -nomirror, -reachable, -floor, -wall)",
+		Run: func(layout *dngn.Layout, opt options) error {
+			chunkOptions := dngn.NewDefaultChunkOptions()
+			chunkOptions.FloorValue = opt.Floor
+			chunkOptions.WallValue = opt.Wall
+			chunkOptions.DropChance = float32(opt.Drop)
+			chunkOptions.Mirror = !opt.NoMirror
+			result, err := layout.GenerateChunks(chunkOptions)
+			if err != nil || !opt.Reachable {
+				return err
+			}
+			platformerOptions := dngn.NewDefaultPlatformerOptions()
+			platformerOptions.SolidValues = []rune{opt.Wall}
+			entrance, exit := result.Entrance(), result.Exit()
+			start := layout.ClosestChar(entrance.X+entrance.W/2, entrance.Y+entrance.H/2, opt.Floor)
+			end := layout.ClosestChar(exit.X+exit.W/2, exit.Y+exit.H/2, opt.Floor)
+			for layout.Get(end.X, end.Y+1) == opt.Floor {
+				end.Y++
+			}
+			_, err = layout.ConnectPlatforms(start, end, platformerOptions)
+			return err
+		},
+	},
+
+	"cellular": {
+		Description: "Caves grown with cellular automata (uses -wallchance, -iterations, -floor, -wall; try it with -grid for hex caves)",
+		Run: func(layout *dngn.Layout, opt options) error {
+			cellularOptions := dngn.NewDefaultCellularOptions()
+			cellularOptions.FloorValue = opt.Floor
+			cellularOptions.WallValue = opt.Wall
+			cellularOptions.WallChance = float32(opt.WallChance)
+			cellularOptions.Iterations = opt.Iterations
+			_, err := layout.GenerateCellular(cellularOptions)
+			return err
+		},
+	},
+
+	"cyclic": {
+		Description: "Loop-based dungeons with shortcuts and one-way valves (uses -cell, -minroom, -subcycles, -shortcuts, -valves, -wall, -door)",
+		Run: func(layout *dngn.Layout, opt options) error {
+			cyclicOptions := dngn.NewDefaultCyclicOptions()
+			cyclicOptions.WallValue = opt.Wall
+			cyclicOptions.DoorValue = opt.Door
+			cyclicOptions.CellSize = opt.CellSize
+			cyclicOptions.MinimumRoomSize = opt.MinRoomSize
+			cyclicOptions.SubCycles = opt.SubCycles
+			cyclicOptions.Shortcuts = opt.Shortcuts
+			cyclicOptions.Valves = opt.Valves
+			_, err := layout.GenerateCyclic(cyclicOptions)
+			return err
+		},
+	},
+}
+
+// carveRiversAndRoads carves rivers flowing down from the mountains ('^'), and roads between points spread out over the grass
+// (' '), onto a Layout generated from noise.
+func carveRiversAndRoads(layout *dngn.Layout, height dngn.HeightField, rivers, roads int) error {
+
+	sources := layout.Select().FilterByRune('^').PoissonDisc(8)
+	for i := 0; i < rivers && i < len(sources); i++ {
+		riverOptions := dngn.NewDefaultRiverOptions(height)
+		riverOptions.Meander = 0.02
+		if _, err := layout.CarveRiver(sources[i], riverOptions); err != nil {
+			return err
+		}
+	}
+
+	stops := layout.Select().FilterByRune(' ').PoissonDisc(12)
+	for i := 0; i < roads && i+1 < len(stops); i++ {
+		roadOptions := dngn.NewDefaultRoadOptions()
+		roadOptions.Height = height
+		if _, err := layout.CarveRoad(stops[i], stops[i+1], roadOptions); err != nil {
+			return err
+		}
+	}
+
+	return nil
+
+}
+
+// parseNoiseType returns the dngn.NoiseType with the name given.
+func parseNoiseType(name string) (dngn.NoiseType, error) {
+	for noiseType := dngn.NoiseValue; noiseType <= dngn.NoiseOpenSimplex; noiseType++ {
+		if noiseType.String() == name {
+			return noiseType, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown noise type %q; expected value, perlin, simplex, or opensimplex", name)
+}
+
+func main() {
+	if err := run(os.Args[1:], os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "dngn:", strings.TrimPrefix(err.Error(), "dngn: "))
+		os.Exit(1)
+	}
+}
+
+func run(args []string, stdout io.Writer) error {
+
+	flags := flag.NewFlagSet("dngn", flag.ContinueOnError)
+
+	genName := flags.String("gen", "bsp", "Generator to run; see -list")
+	recipe := flags.String("recipe", "", "JSON or YAML pipeline recipe to run instead of a single generator; -width, -height, -seed, and -grid override the recipe's values when set")
+	list := flags.Bool("list", false, "List the available generators and exit")
+	width := flags.Int("width", 80, "Width of the generated map in cells")
+	height := flags.Int("height", 45, "Height of the generated map in cells")
+	seed := flags.Int64("seed", 0, "Seed for the random number generator; 0 picks one based on the current time")
+	grid := flags.String("grid", "square", "Shape of the map's cells: square, hex-pointy-odd, hex-pointy-even, hex-flat-odd, or hex-flat-even (hex maps are written in offset coordinates)")
+	format := flags.String("format", "", "Output format: ascii, json, or png (defaults to the -o file's extension, or ascii)")
+	outPath := flags.String("o", "", "File to write the output to (defaults to stdout)")
+	scale := flags.Int("scale", 8, "Size of each cell in pixels for PNG output")
+	trace := flags.Bool("trace", false, "Print each step the generator takes to stderr")
+
+	floor := flags.String("floor", " ", "Rune to use for floors")
+	wall := flags.String("wall", "x", "Rune to use for walls")
+	door := flags.String("door", "#", "Rune to use for doors")
+
+	opt := options{}
+	flags.IntVar(&opt.Splits, "splits", 10, "bsp: Number of times to split the map")
+	flags.IntVar(&opt.MinRoomSize, "minroom", 4, "bsp, cyclic: Minimum size of each room")
+	flags.BoolVar(&opt.Carve, "carve", false, "bsp: Carve a room inside each partition and connect them with corridors")
+	flags.IntVar(&opt.Padding, "padding", 1, "bsp: Minimum wall thickness between a carved room and its partition's edges")
+	flags.IntVar(&opt.Doors, "doors", 1, "bsp: Number of doors on each wall between rooms that gets doors")
+	flags.IntVar(&opt.DoorWidth, "doorwidth", 1, "bsp: Width of each door in cells")
+	flags.Float64Var(&opt.Loops, "loops", 0.25, "bsp: Chance (0 - 1) of extra doors that create loops")
+	flags.StringVar(&opt.Placement, "placement", "random", "bsp: Where doors go along walls: random, centered, or corners")
+	flags.BoolVar(&opt.SkipBorder, "skipborder", false, "bsp: Don't guarantee doors for rooms touching the map's edges")
+	flags.BoolVar(&opt.Connected, "connected", true, "bsp: Make sure every room is reachable, adding doors where needed")
+	flags.IntVar(&opt.Rooms, "rooms", 6, "rooms: Number of rooms to place")
+	flags.IntVar(&opt.MinWidth, "minw", 3, "rooms: Minimum room width")
+	flags.IntVar(&opt.MinHeight, "minh", 3, "rooms: Minimum room height")
+	flags.IntVar(&opt.MaxWidth, "maxw", 5, "rooms: Maximum room width")
+	flags.IntVar(&opt.MaxHeight, "maxh", 5, "rooms: Maximum room height")
+	flags.BoolVar(&opt.Connect, "connect", true, "rooms: Connect the rooms with pathways")
+	flags.Float64Var(&opt.Fill, "fill", 0.5, "drunk, dla: Percentage of the map to fill with floor (0 - 1)")
+	flags.IntVar(&opt.Walkers, "walkers", 1, "drunk: Number of walkers to start with")
+	flags.IntVar(&opt.MaxWalkers, "maxwalkers", 0, "drunk: Most walkers that can walk at once when spawning new ones")
+	flags.Float64Var(&opt.Momentum, "momentum", 0, "drunk: Chance (0 - 1) of walkers continuing in the same direction")
+	flags.Float64Var(&opt.BiasX, "biasx", 0, "drunk: Preference (-1 - 1) for walking right (positive) or left (negative)")
+	flags.Float64Var(&opt.BiasY, "biasy", 0, "drunk: Preference (-1 - 1) for walking down (positive) or up (negative)")
+	flags.Float64Var(&opt.Spawn, "spawn", 0, "drunk: Chance (0 - 1) each step of a walker spawning another")
+	flags.Float64Var(&opt.Death, "death", 0, "drunk: Chance (0 - 1) each step of a walker stopping")
+	flags.IntVar(&opt.Steps, "steps", 0, "drunk: Steps a walker takes before restarting (0 for no limit)")
+	flags.BoolVar(&opt.RestartFromFloor, "restart", false, "drunk: Restart walkers on existing floor, keeping caves connected")
+	flags.IntVar(&opt.Brush, "brush", 1, "drunk: Size of the area carved by each step")
+	flags.IntVar(&opt.Border, "border", 0, "drunk, dla: Thickness of the solid wall border walkers and particles stay out of")
+	flags.IntVar(&opt.CellSize, "cell", 8, "cyclic: Size of the grid cell each room is placed in")
+	flags.IntVar(&opt.SubCycles, "subcycles", 2, "cyclic: Number of smaller loops to add onto the main loop")
+	flags.IntVar(&opt.Shortcuts, "shortcuts", 1, "cyclic: Number of one-way shortcuts back towards the start")
+	flags.IntVar(&opt.Valves, "valves", 1, "cyclic: Number of one-way valves to place on loops")
+	flags.StringVar(&opt.Particles, "particles", "edges", "dla: Where particles start: edges or anywhere")
+	flags.Float64Var(&opt.Attraction, "attract", 0, "dla: Chance (0 - 1) each step of a particle moving towards the center")
+	flags.BoolVar(&opt.MirrorX, "mirrorx", false, "dla: Mirror the caverns from left to right")
+	flags.BoolVar(&opt.MirrorY, "mirrory", false, "dla: Mirror the caverns from top to bottom")
+	flags.StringVar(&opt.Noise, "noise", "simplex", "noise, biomes: Type of noise: value, perlin, simplex, or opensimplex")
+	flags.Float64Var(&opt.Frequency, "freq", 0.05, "noise, biomes: Frequency of the noise; smaller values make larger features")
+	flags.IntVar(&opt.Octaves, "octaves", 4, "noise, biomes: Number of layers of noise to add together for finer detail")
+	flags.BoolVar(&opt.Ridged, "ridged", false, "noise, biomes: Fold the noise into sharp ridges")
+	flags.IntVar(&opt.StreetWidth, "street", 2, "town: Width of the streets")
+	flags.IntVar(&opt.BlockSize, "block", 14, "town: Minimum size of the blocks between streets")
+	flags.IntVar(&opt.LotSize, "lot", 7, "town: Minimum width of each lot along its street")
+	flags.Float64Var(&opt.Buildings, "buildings", 0.85, "town: Chance (0 - 1) of each lot having a building")
+	flags.BoolVar(&opt.NoPlaza, "noplaza", false, "town: Don't turn the center block into a plaza")
+	flags.Float64Var(&opt.Drop, "drop", 0.25, "chunks: Chance (0 - 1) of the path dropping down a row at each step")
+	flags.BoolVar(&opt.NoMirror, "nomirror", false, "chunks: Don't mirror templates at random")
+	flags.BoolVar(&opt.Reachable, "reachable", false, "chunks: Add ladders ('H') and platforms ('=') until the exit can be reached by jumping and climbing")
+	flags.Float64Var(&opt.WallChance, "wallchance", 0.45, "cellular: Chance (0 - 1) of each cell starting out as a wall")
+	flags.IntVar(&opt.Iterations, "iterations", 5, "cellular: Number of times to run the automata's rules")
+	flags.IntVar(&opt.Rivers, "rivers", 0, "noise: Number of rivers to carve down from the mountains")
+	flags.IntVar(&opt.Roads, "roads", 0, "noise: Number of roads to carve between points on the grass")
+	flags.Float64Var(&opt.Warp, "warp", 0, "noise, biomes: How far (in cells) to warp the noise by another noise field")
+
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *list {
+		names := []string{}
+		for name := range generators {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			fmt.Fprintf(stdout, "%-8s %s\n", name, generators[name].Description)
+		}
+		return nil
+	}
+
+	gen, exists := generators[*genName]
+	if !exists && *recipe == "" {
+		return fmt.Errorf("unknown generator %q; run with -list to see the available generators", *genName)
+	}
+
+	gridType, err := dngn.ParseGridType(*grid)
+	if err != nil {
+		return err
+	}
+
+	var pipeline *dngn.Pipeline
+
+	if *recipe != "" {
+
+		file, err := os.Open(*recipe)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		if pipeline, err = dngn.ReadPipeline(file); err != nil {
+			return err
+		}
+
+		// Flags given explicitly on the command line take priority over the recipe.
+		flags.Visit(func(f *flag.Flag) {
+			switch f.Name {
+			case "width":
+				pipeline.Width = *width
+			case "height":
+				pipeline.Height = *height
+			case "seed":
+				pipeline.Seed = *seed
+			case "grid":
+				pipeline.Grid = gridType
+			}
+		})
+
+		*width, *height, *seed = pipeline.Width, pipeline.Height, pipeline.Seed
+		gridType = pipeline.Grid
+		*genName = "recipe"
+
+	}
+
+	if *width <= 0 || *height <= 0 {
+		return fmt.Errorf("map size must be positive, got %dx%d", *width, *height)
+	}
+
+	if opt.Floor, err = parseRune("floor", *floor); err != nil {
+		return err
+	}
+	if opt.Wall, err = parseRune("wall", *wall); err != nil {
+		return err
+	}
+	if opt.Door, err = parseRune("door", *door); err != nil {
+		return err
+	}
+
+	if *seed == 0 {
+		*seed = time.Now().UnixNano()
+	}
+
+	if *format == "" {
+		*format = strings.TrimPrefix(strings.ToLower(filepath.Ext(*outPath)), ".")
+		if *format == "" || *format == "txt" {
+			*format = "ascii"
+		}
+	}
+
+	write, exists := writers[*format]
+	if !exists {
+		return fmt.Errorf("unknown output format %q; expected ascii, json, or png", *format)
+	}
+
+	layout := dngn.NewLayout(*width, *height)
+	layout.Grid = gridType
+
+	if *trace {
+		layout.OnStep = func(step dngn.GenerationStep) {
+			fmt.Fprintf(os.Stderr, "%s %s x:%d y:%d w:%d h:%d", step.Generator, step.Kind, step.X, step.Y, step.W, step.H)
+			if step.Kind == dngn.StepSplit || step.Kind == dngn.StepSplitRejected {
+				fmt.Fprintf(os.Stderr, " vertical:%t at:%d", step.Vertical, step.SplitPosition)
+			}
+			fmt.Fprintln(os.Stderr)
+		}
+	}
+
+	if pipeline != nil {
+		if err := pipeline.Run(layout, *seed); err != nil {
+			return err
+		}
+	} else {
+		layout.SetRNG(rand.NewSource(*seed))
+		if err := gen.Run(layout, opt); err != nil {
+			return err
+		}
+	}
+
+	if *outPath == "" {
+		return write(stdout, layout, output{Generator: *genName, Seed: *seed, Scale: *scale})
+	}
+
+	file, err := os.Create(*outPath)
+	if err != nil {
+		return err
+	}
+
+	if err := write(file, layout, output{Generator: *genName, Seed: *seed, Scale: *scale}); err != nil {
+		file.Close()
+		return err
+	}
+
+	// Writes can fail when the file's closed (when the disk is full, say), so the error from Close matters here.
+	return file.Close()
+
+}
+
+// parseRune returns the single rune contained in value, which was passed through the flag of the given name.
+func parseRune(name, value string) (rune, error) {
+	runes := []rune(value)
+	if len(runes) != 1 {
+		return 0, fmt.Errorf("-%s must be a single character, got %q", name, value)
+	}
+	return runes[0], nil
+}
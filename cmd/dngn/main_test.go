@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestGenerators checks that every generator produces a map, and the same map each time it's run with the same seed.
+func TestGenerators(t *testing.T) {
+
+	for name := range generators {
+
+		args := []string{"-gen", name, "-seed", "1", "-width", "60", "-height", "40"}
+
+		first := &bytes.Buffer{}
+		if err := run(args, first); err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+
+		if first.Len() == 0 {
+			t.Errorf("%s: no output", name)
+		}
+
+		second := &bytes.Buffer{}
+		if err := run(args, second); err != nil {
+			t.Errorf("%s: second run: %v", name, err)
+			continue
+		}
+
+		if !bytes.Equal(first.Bytes(), second.Bytes()) {
+			t.Errorf("%s: the same seed produced different maps", name)
+		}
+
+	}
+
+}
+
+// TestOutputFile checks that each output format is written to the -o file.
+func TestOutputFile(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "dngn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, name := range []string{"map.txt", "map.json", "map.png"} {
+
+		path := filepath.Join(dir, name)
+
+		if err := run([]string{"-seed", "1", "-o", path}, &bytes.Buffer{}); err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+
+		if info, err := os.Stat(path); err != nil || info.Size() == 0 {
+			t.Errorf("%s: nothing was written (%v)", name, err)
+		}
+
+	}
+
+}
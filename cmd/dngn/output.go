@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"strings"
+
+	"github.com/solarlune/dngn"
+)
+
+// output holds information about the generation run that some output formats include alongside the map data.
+type output struct {
+	Generator string
+	Seed      int64
+	Scale     int
+}
+
+// writers maps each output format name to the function that writes a Layout in that format.
+var writers = map[string]func(w io.Writer, layout *dngn.Layout, out output) error{
+	"ascii": writeASCII,
+	"json":  writeJSON,
+	"png":   writePNG,
+}
+
+// writeASCII writes the Layout's data as plain text, one line per row.
+func writeASCII(w io.Writer, layout *dngn.Layout, out output) error {
+	_, err := io.WriteString(w, strings.Join(rows(layout), "\n")+"\n")
+	return err
+}
+
+// writeJSON writes the Layout's data, along with its size, generator, and seed, as a JSON object.
+func writeJSON(w io.Writer, layout *dngn.Layout, out output) error {
+
+	data := struct {
+		Generator string   `json:"generator"`
+		Seed      int64    `json:"seed"`
+		Width     int      `json:"width"`
+		Height    int      `json:"height"`
+		Rows      []string `json:"rows"`
+	}{
+		Generator: out.Generator,
+		Seed:      out.Seed,
+		Width:     layout.Width,
+		Height:    layout.Height,
+		Rows:      rows(layout),
+	}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(data)
+
+}
+
+// writePNG draws the Layout as an image, with each cell being a Scale x Scale square colored according to its rune.
+func writePNG(w io.Writer, layout *dngn.Layout, out output) error {
+
+	if out.Scale <= 0 {
+		return fmt.Errorf("-scale must be positive, got %d", out.Scale)
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, layout.Width*out.Scale, layout.Height*out.Scale))
+
+	for y := 0; y < layout.Height; y++ {
+		for x := 0; x < layout.Width; x++ {
+			c := runeColor(layout.Get(x, y))
+			for py := 0; py < out.Scale; py++ {
+				for px := 0; px < out.Scale; px++ {
+					img.Set(x*out.Scale+px, y*out.Scale+py, c)
+				}
+			}
+		}
+	}
+
+	return png.Encode(w, img)
+
+}
+
+// rows returns each row of the Layout's data as a string.
+func rows(layout *dngn.Layout) []string {
+	lines := make([]string, 0, layout.Height)
+	for y := 0; y < layout.Height; y++ {
+		lines = append(lines, string(layout.Data[y]))
+	}
+	return lines
+}
+
+// palette holds the colors used for the runes the generators use by default.
+var palette = map[rune]color.RGBA{
+	' ': {224, 214, 190, 255}, // Floor
+	'.': {200, 190, 166, 255}, // Alternate floor
+	'x': {48, 44, 56, 255},    // Wall
+	'#': {164, 96, 48, 255},   // Door
+	0:   {0, 0, 0, 255},
+}
+
+// runeColor returns the color used to draw the given rune. Runes without an entry in the palette get a color derived from their
+// value, so the same rune always draws in the same color.
+func runeColor(r rune) color.RGBA {
+	if c, exists := palette[r]; exists {
+		return c
+	}
+	h := uint32(r) * 2654435761
+	return color.RGBA{uint8(64 + h>>8%160), uint8(64 + h>>16%160), uint8(64 + h>>24%160), 255}
+}
@@ -0,0 +1,424 @@
+package dngn
+
+import (
+	"context"
+)
+
+// CyclicOptions configures Layout.GenerateCyclic().
+type CyclicOptions struct {
+	WallValue  rune // The rune the Layout is filled with before rooms and corridors are carved out.
+	DoorValue  rune // The rune used for doors; if 0, no doors are drawn.
+	ValveValue rune // The rune used for the doors of one-way passages (valves and shortcuts); if 0, DoorValue is used.
+
+	// The Layout is divided into a grid of CellSize x CellSize cells, and each room is placed inside its own cell; rooms in
+	// neighboring cells can be connected with corridors.
+	CellSize        int
+	MinimumRoomSize int // The minimum width and height of each room; this can be at most CellSize - 2.
+
+	SubCycles int // The number of smaller loops to add onto the main loop.
+	Shortcuts int // The number of one-way shortcuts to add between rooms that are far apart on the map's graph.
+	Valves    int // The number of passages on loops to turn into one-way valves.
+}
+
+// NewDefaultCyclicOptions returns a new CyclicOptions with default values.
+func NewDefaultCyclicOptions() CyclicOptions {
+	return CyclicOptions{
+		WallValue:       'x',
+		DoorValue:       '#',
+		ValveValue:      '=',
+		CellSize:        8,
+		MinimumRoomSize: 3,
+		SubCycles:       2,
+		Shortcuts:       1,
+		Valves:          1,
+	}
+}
+
+// validate returns an error if the options can't be used to generate a map.
+func (options CyclicOptions) validate() error {
+
+	if options.MinimumRoomSize < 1 {
+		return invalidArgument("GenerateCyclic", "minimum room size must be at least 1, got %d", options.MinimumRoomSize)
+	}
+
+	if options.CellSize < options.MinimumRoomSize+2 {
+		return invalidArgument("GenerateCyclic", "cell size must be at least the minimum room size + 2 (%d), got %d", options.MinimumRoomSize+2, options.CellSize)
+	}
+
+	if options.SubCycles < 0 || options.Shortcuts < 0 || options.Valves < 0 {
+		return invalidArgument("GenerateCyclic", "sub-cycle, shortcut, and valve counts must be non-negative, got %d, %d, and %d", options.SubCycles, options.Shortcuts, options.Valves)
+	}
+
+	return nil
+
+}
+
+// CyclicPassage is a connection between two rooms generated through Layout.GenerateCyclic().
+type CyclicPassage struct {
+	From, To *Room
+	OneWay   bool // If the passage can only be taken from From to To.
+	Shortcut bool // If the passage is a shortcut between two rooms that were far apart; shortcuts are always one-way, leading back towards the start.
+}
+
+// CyclicResult is the result of generating a map through Layout.GenerateCyclic(). The Start and Goal rooms are also tagged with
+// RoleStart and RoleExit.
+type CyclicResult struct {
+	RoomGraph
+	Passages []*CyclicPassage
+	Start    *Room
+	Goal     *Room
+}
+
+// Passage returns the passage between the two rooms provided (in either direction), or nil if they aren't connected.
+func (result *CyclicResult) Passage(a, b *Room) *CyclicPassage {
+	for _, passage := range result.Passages {
+		if (passage.From == a && passage.To == b) || (passage.From == b && passage.To == a) {
+			return passage
+		}
+	}
+	return nil
+}
+
+// Reachable returns the rooms that can be reached from the room provided, taking one-way passages into account (unlike the
+// rooms' Connected lists, which include one-way passages in both directions).
+func (result *CyclicResult) Reachable(from *Room) map[*Room]bool {
+
+	reachable := map[*Room]bool{from: true}
+	toCheck := []*Room{from}
+
+	for len(toCheck) > 0 {
+
+		next := toCheck[0]
+		toCheck = toCheck[1:]
+
+		for _, passage := range result.Passages {
+
+			var other *Room
+
+			if passage.From == next {
+				other = passage.To
+			} else if passage.To == next && !passage.OneWay {
+				other = passage.From
+			}
+
+			if other != nil && !reachable[other] {
+				reachable[other] = true
+				toCheck = append(toCheck, other)
+			}
+
+		}
+
+	}
+
+	return reachable
+
+}
+
+// stronglyConnected returns if every room can be reached from every other room, taking one-way passages into account.
+func (result *CyclicResult) stronglyConnected() bool {
+
+	if len(result.Reachable(result.Start)) != len(result.Rooms) {
+		return false
+	}
+
+	for _, room := range result.Rooms {
+		if !result.Reachable(room)[result.Start] {
+			return false
+		}
+	}
+
+	return true
+
+}
+
+// GenerateCyclic generates a map designed around loops, inspired by cyclic dungeon generation (as used in Unexplored). It starts
+// with a main loop of rooms with the start and goal rooms on opposite sides, so there are two ways to get from one to the other;
+// then it adds smaller loops onto it, one-way shortcuts that lead from far-away rooms back towards the start, and one-way valves
+// on loops. One-way passages are only added where every room can still reach every other room (so the player can always get back
+// to the entrance).
+// The Layout is filled with WallValue, and then rooms (one per grid cell; see CyclicOptions) and the corridors between them are
+// carved out with ' '.
+// An error is returned if the options are invalid, or if the Layout is too small to fit at least a 2x2 grid of cells.
+// GenerateCyclic is the same as GenerateCyclicContext() with a background context.
+func (layout *Layout) GenerateCyclic(options CyclicOptions) (*CyclicResult, error) {
+	return layout.GenerateCyclicContext(context.Background(), options)
+}
+
+// GenerateCyclicContext works like GenerateCyclic(), but stops and returns an error if the context is cancelled or the Layout's
+// StepBudget runs out before generation finishes.
+func (layout *Layout) GenerateCyclicContext(ctx context.Context, options CyclicOptions) (*CyclicResult, error) {
+
+	if err := layout.validateSize("GenerateCyclic"); err != nil {
+		return nil, err
+	}
+
+	if err := options.validate(); err != nil {
+		return nil, err
+	}
+
+	columns, rows := layout.Width/options.CellSize, layout.Height/options.CellSize
+
+	if columns < 2 || rows < 2 {
+		return nil, invalidArgument("GenerateCyclic", "a %dx%d layout is too small for a 2x2 grid of %d-cell rooms", layout.Width, layout.Height, options.CellSize)
+	}
+
+	gen := layout.generation(ctx, "cyclic")
+
+	result := &CyclicResult{
+		RoomGraph: RoomGraph{Rooms: []*Room{}, Doors: []Door{}},
+		Passages:  []*CyclicPassage{},
+	}
+
+	// The room in each grid cell.
+	grid := map[Position]*Room{}
+	cells := map[*Room]Position{}
+
+	addRoom := func(cell Position) *Room {
+		room := NewRoom(cell.X, cell.Y, 1, 1)
+		grid[cell] = room
+		cells[room] = cell
+		result.Rooms = append(result.Rooms, room)
+		return room
+	}
+
+	connect := func(a, b *Room) *CyclicPassage {
+		passage := &CyclicPassage{From: a, To: b}
+		result.Passages = append(result.Passages, passage)
+		a.connect(b)
+		return passage
+	}
+
+	free := func(cell Position) bool {
+		return cell.X >= 0 && cell.Y >= 0 && cell.X < columns && cell.Y < rows && grid[cell] == nil
+	}
+
+	// The main loop runs around the edges of a rectangle of cells.
+	loopW := 2 + layout.RNG.Intn(columns*2/3)
+	loopH := 2 + layout.RNG.Intn(rows*2/3)
+	if loopW > columns {
+		loopW = columns
+	}
+	if loopH > rows {
+		loopH = rows
+	}
+
+	loopX := layout.RNG.Intn(columns - loopW + 1)
+	loopY := layout.RNG.Intn(rows - loopH + 1)
+
+	loop := []*Room{}
+
+	for x := 0; x < loopW; x++ {
+		loop = append(loop, addRoom(Position{loopX + x, loopY}))
+	}
+	for y := 1; y < loopH; y++ {
+		loop = append(loop, addRoom(Position{loopX + loopW - 1, loopY + y}))
+	}
+	for x := loopW - 2; x >= 0; x-- {
+		loop = append(loop, addRoom(Position{loopX + x, loopY + loopH - 1}))
+	}
+	for y := loopH - 2; y > 0; y-- {
+		loop = append(loop, addRoom(Position{loopX, loopY + y}))
+	}
+
+	for i, room := range loop {
+		connect(room, loop[(i+1)%len(loop)])
+	}
+
+	startIndex := layout.RNG.Intn(len(loop))
+	result.Start = loop[startIndex]
+	result.Goal = loop[(startIndex+len(loop)/2)%len(loop)]
+
+	// Sub-cycles replace a passage between two rooms with a loop by running a detour alongside it.
+	for i := 0; i < options.SubCycles; i++ {
+
+		for attempt := 0; attempt < 20; attempt++ {
+
+			passage := result.Passages[layout.RNG.Intn(len(result.Passages))]
+			a, b := cells[passage.From], cells[passage.To]
+
+			// The detour runs perpendicular to the passage.
+			offset := Position{b.Y - a.Y, b.X - a.X}
+			if layout.RNG.Float32() >= 0.5 {
+				offset = Position{-offset.X, -offset.Y}
+			}
+
+			length := 1 + layout.RNG.Intn(2)
+			detourA, detourB := []Position{}, []Position{}
+
+			for j := 1; j <= length; j++ {
+				detourA = append(detourA, Position{a.X + offset.X*j, a.Y + offset.Y*j})
+				detourB = append(detourB, Position{b.X + offset.X*j, b.Y + offset.Y*j})
+			}
+
+			fits := true
+			for _, cell := range append(append([]Position{}, detourA...), detourB...) {
+				if !free(cell) {
+					fits = false
+					break
+				}
+			}
+
+			if !fits {
+				continue
+			}
+
+			prev := passage.From
+			for _, cell := range detourA {
+				room := addRoom(cell)
+				connect(prev, room)
+				prev = room
+			}
+
+			for j := len(detourB) - 1; j >= 0; j-- {
+				room := addRoom(detourB[j])
+				connect(prev, room)
+				prev = room
+			}
+
+			connect(prev, passage.To)
+
+			break
+
+		}
+
+	}
+
+	hops := result.Start.HopsFrom()
+
+	// Shortcuts connect neighboring cells that are far apart on the graph, leading back towards the start.
+	for i := 0; i < options.Shortcuts; i++ {
+
+		var from, to *Room
+		longest := 2
+
+		for _, room := range result.Rooms {
+
+			for _, dir := range []Position{{1, 0}, {0, 1}} {
+
+				other := grid[Position{cells[room].X + dir.X, cells[room].Y + dir.Y}]
+
+				if other == nil || result.Passage(room, other) != nil {
+					continue
+				}
+
+				if distance := room.HopsFrom()[other]; distance > longest {
+					longest = distance
+					from, to = room, other
+				}
+
+			}
+
+		}
+
+		if from == nil {
+			break
+		}
+
+		if hops[from] < hops[to] {
+			from, to = to, from
+		}
+
+		passage := connect(from, to)
+		passage.OneWay = true
+		passage.Shortcut = true
+
+	}
+
+	// Valves make passages on loops one-way, leading away from the start; they're only kept if every room can still be reached from
+	// every other room.
+	candidates := []*CyclicPassage{}
+	for _, passage := range result.Passages {
+		if !passage.OneWay {
+			candidates = append(candidates, passage)
+		}
+	}
+
+	layout.RNG.Shuffle(len(candidates), func(i, j int) { candidates[i], candidates[j] = candidates[j], candidates[i] })
+
+	valves := 0
+
+	for _, passage := range candidates {
+
+		if valves >= options.Valves {
+			break
+		}
+
+		if hops[passage.From] > hops[passage.To] {
+			passage.From, passage.To = passage.To, passage.From
+		}
+
+		passage.OneWay = true
+
+		if result.stronglyConnected() {
+			valves++
+		} else {
+			passage.OneWay = false
+		}
+
+	}
+
+	result.Start.AddTag(RoleStart)
+	result.Goal.AddTag(RoleExit)
+
+	// Now that the graph is done, lay it out onto the Layout.
+	layout.Select().Fill(options.WallValue)
+
+	// roomSpan returns a random start position and size for a room inside the grid cell spanning from start to start+CellSize.
+	roomSpan := func(start int) (int, int) {
+		available := options.CellSize - 2
+		size := options.MinimumRoomSize + layout.RNG.Intn(available-options.MinimumRoomSize+1)
+		return start + 1 + layout.RNG.Intn(available-size+1), size
+	}
+
+	for _, room := range result.Rooms {
+
+		cell := cells[room]
+		room.X, room.W = roomSpan(cell.X * options.CellSize)
+		room.Y, room.H = roomSpan(cell.Y * options.CellSize)
+
+		for y := room.Y; y < room.Y+room.H; y++ {
+			for x := room.X; x < room.X+room.W; x++ {
+				layout.Set(x, y, ' ')
+			}
+		}
+
+		if err := gen.step(GenerationStep{Kind: StepRoom, X: room.X, Y: room.Y, W: room.W, H: room.H, Room: room}); err != nil {
+			return nil, err
+		}
+
+	}
+
+	for _, passage := range result.Passages {
+
+		doorValue := options.DoorValue
+		if passage.OneWay && options.ValveValue != 0 {
+			doorValue = options.ValveValue
+		}
+
+		// Corridors jog along the border between the two rooms' cells, so they never run into other rooms' corridors.
+		from, to := passage.From.Center(), passage.To.Center()
+		var path []Position
+
+		if cells[passage.From].Y == cells[passage.To].Y {
+			border := maxInt(cells[passage.From].X, cells[passage.To].X) * options.CellSize
+			path = linePath(from, Position{border, from.Y}, Position{border, to.Y}, to)
+		} else {
+			border := maxInt(cells[passage.From].Y, cells[passage.To].Y) * options.CellSize
+			path = linePath(from, Position{from.X, border}, Position{to.X, border}, to)
+		}
+
+		if err := layout.carveCorridor(gen, passage.From, passage.To, path, options.WallValue, doorValue); err != nil {
+			return nil, err
+		}
+
+		result.Doors = append(result.Doors, passage.From.Doors[len(passage.From.Doors)-1], passage.To.Doors[len(passage.To.Doors)-1])
+
+	}
+
+	for _, room := range result.Rooms {
+		room.Cells = layout.areaCells(room.X, room.Y, room.W, room.H, ' ')
+	}
+
+	return result, nil
+
+}
@@ -0,0 +1,45 @@
+package dngn
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestCyclic checks that the same seed generates the same map, and that every room can still reach every other room once the
+// one-way passages are added.
+func TestCyclic(t *testing.T) {
+
+	generate := func(seed int64) (*Layout, *CyclicResult) {
+
+		layout := NewLayout(60, 40)
+		layout.RNG = rand.New(rand.NewSource(seed))
+
+		result, err := layout.GenerateCyclic(NewDefaultCyclicOptions())
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+
+		return layout, result
+
+	}
+
+	for seed := int64(1); seed <= 5; seed++ {
+
+		first, result := generate(seed)
+		second, _ := generate(seed)
+
+		if first.DataToString() != second.DataToString() {
+			t.Errorf("seed %d: the same seed generated different maps", seed)
+		}
+
+		if !result.stronglyConnected() {
+			t.Errorf("seed %d: not every room can reach every other room", seed)
+		}
+
+		if !result.HasCycles() {
+			t.Errorf("seed %d: the map has no loops", seed)
+		}
+
+	}
+
+}
@@ -0,0 +1,457 @@
+package dngn
+
+import "sort"
+
+// DetectRoomsOptions configures Layout.DetectRooms().
+type DetectRoomsOptions struct {
+	FloorValues []rune // The runes that can be walked on.
+	DoorValues  []rune // The runes that mark doors. Doors can be walked through, but always separate the rooms on either side.
+
+	// Floor areas narrower than MinimumRoomWidth are considered corridors, rather than rooms.
+	MinimumRoomWidth int
+
+	// Areas of floor that bulge out from each other are detected as separate rooms, unless the opening between them is at most
+	// MergeTolerance cells narrower (in half-widths) than the smaller area. Higher values merge more areas together into fewer,
+	// larger rooms.
+	MergeTolerance int
+}
+
+// NewDefaultDetectRoomsOptions returns a new DetectRoomsOptions with default values, matching the runes GenerateBSP() uses.
+func NewDefaultDetectRoomsOptions() DetectRoomsOptions {
+	return DetectRoomsOptions{
+		FloorValues:      []rune{' '},
+		DoorValues:       []rune{'#'},
+		MinimumRoomWidth: 3,
+		MergeTolerance:   1,
+	}
+}
+
+// RoomDetection is the result of detecting rooms in a Layout through Layout.DetectRooms().
+type RoomDetection struct {
+	RoomGraph
+	Corridors []Selection // The separate areas of floor that aren't part of any room.
+}
+
+// DetectRooms splits the floor of an existing Layout (like one made by hand and loaded through NewLayoutFromStringArray(), or one
+// made by any of the Generate functions) into rooms and corridors, so that the same room-based tools (hop counts, AssignRoles(),
+// PlaceLocks(), and so on) can be used on it.
+// Rooms are found by looking at how far each floor cell is from the nearest wall: areas at least MinimumRoomWidth cells wide are
+// rooms, and narrower areas are corridors. Wide areas that are only joined by narrower openings (like two rooms with an open
+// doorway between them) are split into separate rooms using a watershed. Doors always separate the rooms on either side of them.
+// Rooms are connected if they touch each other, or if they're joined through doors or corridors. Each door leading out of a room
+// is added to the room's Doors once for each room it leads to.
+// An error is returned if the Layout is empty, or if the options are invalid.
+func (layout *Layout) DetectRooms(options DetectRoomsOptions) (*RoomDetection, error) {
+
+	if err := layout.validateSize("DetectRooms"); err != nil {
+		return nil, err
+	}
+
+	if len(options.FloorValues) == 0 {
+		return nil, invalidArgument("DetectRooms", "no floor values given")
+	}
+
+	if options.MinimumRoomWidth < 1 {
+		return nil, invalidArgument("DetectRooms", "minimum room width must be at least 1, got %d", options.MinimumRoomWidth)
+	}
+
+	if options.MergeTolerance < 0 {
+		return nil, invalidArgument("DetectRooms", "merge tolerance can't be negative, got %d", options.MergeTolerance)
+	}
+
+	isDoor := func(x, y int) bool {
+		value := layout.Get(x, y)
+		for _, door := range options.DoorValues {
+			if value == door {
+				return true
+			}
+		}
+		return false
+	}
+
+	inBounds := func(x, y int) bool {
+		return x >= 0 && y >= 0 && x < layout.Width && y < layout.Height
+	}
+
+	isFloor := func(x, y int) bool {
+		if !inBounds(x, y) || isDoor(x, y) {
+			return false
+		}
+		value := layout.Get(x, y)
+		for _, floor := range options.FloorValues {
+			if value == floor {
+				return true
+			}
+		}
+		return false
+	}
+
+	cardinal := []Position{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+	allDirections := []Position{{1, 0}, {-1, 0}, {0, 1}, {0, -1}, {1, 1}, {-1, 1}, {1, -1}, {-1, -1}}
+
+	// Distance transform; each floor cell's distance (in steps, including diagonal ones) from the nearest cell that isn't floor.
+	distance := make([][]int, layout.Height)
+	toCheck := []Position{}
+
+	for y := 0; y < layout.Height; y++ {
+		distance[y] = make([]int, layout.Width)
+		for x := 0; x < layout.Width; x++ {
+			if !isFloor(x, y) {
+				continue
+			}
+			for _, dir := range allDirections {
+				if !isFloor(x+dir.X, y+dir.Y) {
+					distance[y][x] = 1
+					toCheck = append(toCheck, Position{x, y})
+					break
+				}
+			}
+		}
+	}
+
+	maxDistance := 0
+
+	for len(toCheck) > 0 {
+
+		next := toCheck[0]
+		toCheck = toCheck[1:]
+
+		if distance[next.Y][next.X] > maxDistance {
+			maxDistance = distance[next.Y][next.X]
+		}
+
+		for _, dir := range allDirections {
+			x, y := next.X+dir.X, next.Y+dir.Y
+			if isFloor(x, y) && distance[y][x] == 0 {
+				distance[y][x] = distance[next.Y][next.X] + 1
+				toCheck = append(toCheck, Position{x, y})
+			}
+		}
+
+	}
+
+	// Cells at least this far from a wall are in the middle of a room.
+	threshold := (options.MinimumRoomWidth + 1) / 2
+
+	levels := make([][]Position, maxDistance+1)
+
+	for y := 0; y < layout.Height; y++ {
+		for x := 0; x < layout.Width; x++ {
+			if d := distance[y][x]; d > 0 {
+				levels[d] = append(levels[d], Position{x, y})
+			}
+		}
+	}
+
+	// Watershed; flood the room cells from the highest (most central) down, starting a new basin for each peak. Labels start at 1.
+	label := make([][]int, layout.Height)
+	for y := range label {
+		label[y] = make([]int, layout.Width)
+	}
+
+	peaks := []int{0}
+
+	for d := maxDistance; d >= threshold; d-- {
+
+		queue := []Position{}
+
+		for _, cell := range levels[d] {
+			for _, dir := range cardinal {
+				x, y := cell.X+dir.X, cell.Y+dir.Y
+				if inBounds(x, y) && label[y][x] != 0 {
+					label[cell.Y][cell.X] = label[y][x]
+					queue = append(queue, cell)
+					break
+				}
+			}
+		}
+
+		flood := func(queue []Position) {
+			for len(queue) > 0 {
+				next := queue[0]
+				queue = queue[1:]
+				for _, dir := range cardinal {
+					x, y := next.X+dir.X, next.Y+dir.Y
+					if inBounds(x, y) && distance[y][x] == d && label[y][x] == 0 {
+						label[y][x] = label[next.Y][next.X]
+						queue = append(queue, Position{x, y})
+					}
+				}
+			}
+		}
+
+		flood(queue)
+
+		for _, cell := range levels[d] {
+			if label[cell.Y][cell.X] == 0 {
+				peaks = append(peaks, d)
+				label[cell.Y][cell.X] = len(peaks) - 1
+				flood([]Position{cell})
+			}
+		}
+
+	}
+
+	// Merge basins that are joined by openings nearly as wide as the smaller of the two.
+	type basinPair struct{ a, b int }
+
+	saddles := map[basinPair]int{}
+
+	for y := 0; y < layout.Height; y++ {
+		for x := 0; x < layout.Width; x++ {
+			for _, dir := range []Position{{1, 0}, {0, 1}} {
+				nx, ny := x+dir.X, y+dir.Y
+				if !inBounds(nx, ny) || label[y][x] == 0 || label[ny][nx] == 0 || label[y][x] == label[ny][nx] {
+					continue
+				}
+				pair := basinPair{label[y][x], label[ny][nx]}
+				if pair.a > pair.b {
+					pair.a, pair.b = pair.b, pair.a
+				}
+				height := distance[y][x]
+				if distance[ny][nx] < height {
+					height = distance[ny][nx]
+				}
+				if height > saddles[pair] {
+					saddles[pair] = height
+				}
+			}
+		}
+	}
+
+	pairs := []basinPair{}
+	for pair := range saddles {
+		pairs = append(pairs, pair)
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		if saddles[pairs[i]] != saddles[pairs[j]] {
+			return saddles[pairs[i]] > saddles[pairs[j]]
+		}
+		if pairs[i].a != pairs[j].a {
+			return pairs[i].a < pairs[j].a
+		}
+		return pairs[i].b < pairs[j].b
+	})
+
+	parent := make([]int, len(peaks))
+	for i := range parent {
+		parent[i] = i
+	}
+
+	var find func(basin int) int
+	find = func(basin int) int {
+		if parent[basin] != basin {
+			parent[basin] = find(parent[basin])
+		}
+		return parent[basin]
+	}
+
+	for _, pair := range pairs {
+
+		a, b := find(pair.a), find(pair.b)
+
+		if a == b {
+			continue
+		}
+
+		lower := peaks[a]
+		if peaks[b] < lower {
+			lower = peaks[b]
+		}
+
+		if saddles[pair] >= lower-options.MergeTolerance {
+			if peaks[b] > peaks[a] {
+				peaks[a] = peaks[b]
+			}
+			parent[b] = a
+		}
+
+	}
+
+	frontier := []Position{}
+
+	for y := 0; y < layout.Height; y++ {
+		for x := 0; x < layout.Width; x++ {
+			if label[y][x] != 0 {
+				label[y][x] = find(label[y][x])
+				frontier = append(frontier, Position{x, y})
+			}
+		}
+	}
+
+	// Grow the rooms back out to their walls, as the cells along them are too close to the walls to count as the middle of a room.
+	for step := 1; step < threshold; step++ {
+
+		next := []Position{}
+
+		for _, cell := range frontier {
+			for _, dir := range allDirections {
+				x, y := cell.X+dir.X, cell.Y+dir.Y
+				if isFloor(x, y) && label[y][x] == 0 && distance[y][x] < threshold {
+					label[y][x] = label[cell.Y][cell.X]
+					next = append(next, Position{x, y})
+				}
+			}
+		}
+
+		frontier = next
+
+	}
+
+	detection := &RoomDetection{
+		RoomGraph: RoomGraph{Rooms: []*Room{}, Doors: []Door{}},
+		Corridors: []Selection{},
+	}
+
+	rooms := map[int]*Room{}
+
+	roomAt := func(x, y int) *Room {
+		if !inBounds(x, y) || label[y][x] == 0 {
+			return nil
+		}
+		return rooms[label[y][x]]
+	}
+
+	for y := 0; y < layout.Height; y++ {
+		for x := 0; x < layout.Width; x++ {
+
+			if label[y][x] == 0 {
+				continue
+			}
+
+			room, exists := rooms[label[y][x]]
+
+			if !exists {
+				room = NewRoom(x, y, 1, 1)
+				room.Cells = Selection{Layout: layout, Cells: map[Position]bool{}}
+				rooms[label[y][x]] = room
+				detection.Rooms = append(detection.Rooms, room)
+			}
+
+			room.Cells.Cells[Position{x, y}] = true
+
+			if x < room.X {
+				room.W += room.X - x
+				room.X = x
+			}
+			if x >= room.X+room.W {
+				room.W = x - room.X + 1
+			}
+			room.H = y - room.Y + 1
+
+			// Rooms that touch each other are connected.
+			for _, dir := range []Position{{-1, 0}, {0, -1}} {
+				if other := roomAt(x+dir.X, y+dir.Y); other != nil && other != room {
+					other.connect(room)
+				}
+			}
+
+		}
+	}
+
+	// Corridors and doors join all of the rooms they touch.
+	visited := map[Position]bool{}
+
+	for y := 0; y < layout.Height; y++ {
+		for x := 0; x < layout.Width; x++ {
+
+			if visited[Position{x, y}] || label[y][x] != 0 || !(isFloor(x, y) || isDoor(x, y)) {
+				continue
+			}
+
+			connector := []Position{}
+			corridor := Selection{Layout: layout, Cells: map[Position]bool{}}
+			touching := []*Room{}
+			queue := []Position{{x, y}}
+			visited[Position{x, y}] = true
+
+			for len(queue) > 0 {
+
+				next := queue[0]
+				queue = queue[1:]
+				connector = append(connector, next)
+
+				if !isDoor(next.X, next.Y) {
+					corridor.Cells[next] = true
+				}
+
+				for _, dir := range cardinal {
+
+					nx, ny := next.X+dir.X, next.Y+dir.Y
+
+					if room := roomAt(nx, ny); room != nil {
+						found := false
+						for _, t := range touching {
+							if t == room {
+								found = true
+								break
+							}
+						}
+						if !found {
+							touching = append(touching, room)
+						}
+					} else if inBounds(nx, ny) && !visited[Position{nx, ny}] && (isFloor(nx, ny) || isDoor(nx, ny)) {
+						visited[Position{nx, ny}] = true
+						queue = append(queue, Position{nx, ny})
+					}
+
+				}
+
+			}
+
+			if len(corridor.Cells) > 0 {
+				detection.Corridors = append(detection.Corridors, corridor)
+			}
+
+			for i, a := range touching {
+				for _, b := range touching[i+1:] {
+					a.connect(b)
+				}
+			}
+
+			// Each door leads from the rooms next to it to every other room the connector reaches. A door directly between two rooms
+			// is only listed in the RoomDetection's Doors once.
+			for _, cell := range connector {
+
+				if !isDoor(cell.X, cell.Y) {
+					continue
+				}
+
+				listed := map[[2]*Room]bool{}
+
+				for _, dir := range cardinal {
+
+					from := roomAt(cell.X+dir.X, cell.Y+dir.Y)
+
+					if from == nil {
+						continue
+					}
+
+					for _, to := range touching {
+
+						if to == from || listed[[2]*Room{from, to}] {
+							continue
+						}
+
+						door := Door{X: cell.X, Y: cell.Y, From: from, To: to}
+						from.Doors = append(from.Doors, door)
+
+						if !listed[[2]*Room{to, from}] {
+							detection.Doors = append(detection.Doors, door)
+						}
+
+						listed[[2]*Room{from, to}] = true
+
+					}
+
+				}
+
+			}
+
+		}
+	}
+
+	return detection, nil
+
+}
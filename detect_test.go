@@ -0,0 +1,80 @@
+package dngn
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestDetectRooms checks that rooms joined by a corridor and by a door are detected as separate, connected rooms.
+func TestDetectRooms(t *testing.T) {
+
+	layout, err := NewLayoutFromStringArray([]string{
+		"xxxxxxxxxxxxxxxxxxx",
+		"x     xxxxxxx     x",
+		"x     xxxxxxx     x",
+		"x                 x",
+		"x     xxxxxxx     x",
+		"x     xxxxxxx     x",
+		"xxx#xxxxxxxxxxxxxxx",
+		"x     xxxxxxxxxxxxx",
+		"x     xxxxxxxxxxxxx",
+		"x     xxxxxxxxxxxxx",
+		"xxxxxxxxxxxxxxxxxxx",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	detection, err := layout.DetectRooms(NewDefaultDetectRoomsOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(detection.Rooms) != 3 {
+		t.Fatalf("expected 3 rooms, got %d", len(detection.Rooms))
+	}
+
+	if len(detection.Corridors) != 1 {
+		t.Errorf("expected 1 corridor, got %d", len(detection.Corridors))
+	}
+
+	if !detection.Connected() {
+		t.Errorf("the rooms aren't connected")
+	}
+
+	left, right, bottom := detection.RoomAt(2, 2), detection.RoomAt(15, 2), detection.RoomAt(2, 8)
+
+	if left == nil || right == nil || bottom == nil || left == right || left == bottom || right == bottom {
+		t.Fatalf("expected three different rooms at the left, right, and bottom")
+	}
+
+	if path := bottom.PathTo(right); len(path) != 3 || path[1] != left {
+		t.Errorf("expected the way from the bottom room to the right room to go through the left room")
+	}
+
+	doors := 0
+	for _, door := range bottom.Doors {
+		if door.X == 3 && door.Y == 6 && door.To == left {
+			doors++
+		}
+	}
+
+	if doors != 1 {
+		t.Errorf("expected the bottom room to have a door into the left room")
+	}
+
+}
+
+// TestDetectRoomsInvalid checks that invalid options are rejected.
+func TestDetectRoomsInvalid(t *testing.T) {
+
+	layout := NewLayout(10, 10)
+
+	options := NewDefaultDetectRoomsOptions()
+	options.FloorValues = nil
+
+	if _, err := layout.DetectRooms(options); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("no floor values: expected ErrInvalidArgument, got %v", err)
+	}
+
+}
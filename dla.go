@@ -0,0 +1,289 @@
+package dngn
+
+import (
+	"context"
+	"fmt"
+)
+
+// dlaParticleSteps is how many steps (per cell in the Layout) a particle in GenerateDLA can take without touching the floor before
+// it's dropped and a new one is spawned.
+const dlaParticleSteps = 10
+
+// DLASpawn is where particles start walking from in Layout.GenerateDLA().
+type DLASpawn int
+
+const (
+	DLASpawnEdges    DLASpawn = iota // Particles start on a random cell along the edges of the area they can walk in.
+	DLASpawnAnywhere                 // Particles start on any random cell that isn't floor yet.
+)
+
+// DLAOptions configures Layout.GenerateDLA().
+type DLAOptions struct {
+	FloorValue       rune    // The rune particles leave behind when they stick.
+	WallValue        rune    // The rune the Layout is filled with before generating.
+	PercentageFilled float32 // How much of the Layout (0 - 1) to fill with FloorValue before stopping.
+
+	// The floor cells the caverns grow out from. If empty, a single seed is placed at the center of the Layout.
+	Seeds []Position
+
+	Spawn DLASpawn // Where particles start walking from.
+
+	// The chance (0 - 1) each step that a particle moves towards the nearest seed rather than in a random direction. Higher values
+	// make the caverns denser and more compact around the seeds, while 0 lets them branch out freely.
+	Attraction float32
+
+	MirrorX bool // If the caverns should be mirrored from left to right.
+	MirrorY bool // If the caverns should be mirrored from top to bottom.
+
+	Padding int // How many cells away from the Layout's edges particles stay, leaving a solid border of walls.
+}
+
+// NewDefaultDLAOptions returns a new DLAOptions for growing caverns out from the center of the Layout, with particles that start
+// from the edges.
+func NewDefaultDLAOptions() DLAOptions {
+	return DLAOptions{
+		FloorValue:       ' ',
+		WallValue:        'x',
+		PercentageFilled: 0.3,
+		Spawn:            DLASpawnEdges,
+		Padding:          1,
+	}
+}
+
+// validate returns an error if the options can't be used to generate a map in the Layout provided.
+func (options DLAOptions) validate(layout *Layout) error {
+
+	// This is written so that NaN fails it too.
+	if !(options.PercentageFilled >= 0 && options.PercentageFilled <= 1) {
+		return invalidArgument("GenerateDLA", "percentage filled must be between 0 and 1, got %v", options.PercentageFilled)
+	}
+
+	if !(options.Attraction >= 0 && options.Attraction <= 1) {
+		return invalidArgument("GenerateDLA", "attraction must be between 0 and 1, got %v", options.Attraction)
+	}
+
+	if options.Spawn != DLASpawnEdges && options.Spawn != DLASpawnAnywhere {
+		return invalidArgument("GenerateDLA", "unknown spawn option %d", options.Spawn)
+	}
+
+	if options.Padding < 0 || options.Padding*2 >= layout.Width || options.Padding*2 >= layout.Height {
+		return invalidArgument("GenerateDLA", "padding of %d doesn't leave any room in a %dx%d layout", options.Padding, layout.Width, layout.Height)
+	}
+
+	// Particles can only stick inside the padding, so they'd never finish if they had to fill more than that.
+	inside := float32((layout.Width - options.Padding*2) * (layout.Height - options.Padding*2))
+
+	if options.PercentageFilled*float32(layout.Area()) > inside {
+		return fmt.Errorf("dngn: GenerateDLA: can't fill %v of the layout: %w", options.PercentageFilled, ErrCannotConverge)
+	}
+
+	return nil
+
+}
+
+// GenerateDLA generates caverns using diffusion-limited aggregation. Starting from a few seed floor cells, particles wander around
+// the Layout one at a time, and stick (turning into floor) as soon as they touch the floor that's already there. Because particles
+// are much more likely to bump into the tips of the caverns than to wander deep into the gaps between them, this grows branching,
+// coral-like caverns that stay connected. Generation stops once at least PercentageFilled of the Layout is filled.
+// It returns a RoomGraph containing a Room for each separate cavern, or an error if the options are invalid (see DLAOptions).
+// GenerateDLA is the same as GenerateDLAContext() with a background context.
+func (layout *Layout) GenerateDLA(options DLAOptions) (*RoomGraph, error) {
+	return layout.GenerateDLAContext(context.Background(), options)
+}
+
+// GenerateDLAContext works like GenerateDLA(), but stops and returns an error if the context is cancelled or the Layout's StepBudget
+// runs out before generation finishes.
+func (layout *Layout) GenerateDLAContext(ctx context.Context, options DLAOptions) (*RoomGraph, error) {
+
+	if err := layout.validateSize("GenerateDLA"); err != nil {
+		return nil, err
+	}
+
+	if err := options.validate(layout); err != nil {
+		return nil, err
+	}
+
+	layout.Select().Fill(options.WallValue)
+
+	gen := layout.generation(ctx, "dla")
+
+	minX, minY := options.Padding, options.Padding
+	maxX, maxY := layout.Width-1-options.Padding, layout.Height-1-options.Padding
+	center := Position{layout.Width / 2, layout.Height / 2}
+
+	fillCount := float32(0)
+	totalArea := float32(layout.Area())
+
+	// stick turns the cell provided (and its mirrored copies) into floor.
+	stick := func(x, y int) error {
+
+		cells := []Position{{x, y}}
+		if options.MirrorX {
+			cells = append(cells, Position{layout.Width - 1 - x, y})
+		}
+		if options.MirrorY {
+			for _, cell := range cells {
+				cells = append(cells, Position{cell.X, layout.Height - 1 - cell.Y})
+			}
+		}
+
+		for _, cell := range cells {
+			if layout.Get(cell.X, cell.Y) != options.FloorValue {
+				layout.Set(cell.X, cell.Y, options.FloorValue)
+				fillCount++
+				if err := gen.step(GenerationStep{Kind: StepCarve, X: cell.X, Y: cell.Y, W: 1, H: 1}); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+
+	}
+
+	seeds := []Position{}
+	for _, seed := range options.Seeds {
+		seeds = append(seeds, Position{clampInt(seed.X, minX, maxX), clampInt(seed.Y, minY, maxY)})
+	}
+	if len(seeds) == 0 {
+		seeds = []Position{center}
+	}
+
+	for _, seed := range seeds {
+		if err := stick(seed.X, seed.Y); err != nil {
+			return nil, err
+		}
+	}
+
+	// nearestSeed returns the seed closest to the position provided. Since seeds are always floor, a particle that keeps moving
+	// towards one is bound to touch the floor.
+	nearestSeed := func(position Position) Position {
+		nearest := seeds[0]
+		for _, seed := range seeds[1:] {
+			if absInt(seed.X-position.X)+absInt(seed.Y-position.Y) < absInt(nearest.X-position.X)+absInt(nearest.Y-position.Y) {
+				nearest = seed
+			}
+		}
+		return nearest
+	}
+
+	directions := []Position{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+
+	touchesFloor := func(x, y int) bool {
+		for _, dir := range directions {
+			nx, ny := x+dir.X, y+dir.Y
+			if nx >= minX && ny >= minY && nx <= maxX && ny <= maxY && layout.Get(nx, ny) == options.FloorValue {
+				return true
+			}
+		}
+		return false
+	}
+
+	// filled returns if every cell in the rectangle provided is floor.
+	filled := func(x1, y1, x2, y2 int) bool {
+		for y := y1; y <= y2; y++ {
+			for x := x1; x <= x2; x++ {
+				if layout.Get(x, y) != options.FloorValue {
+					return false
+				}
+			}
+		}
+		return true
+	}
+
+	// spawn returns a non-floor cell for a new particle to start walking from.
+	spawn := func() (Position, error) {
+
+		anywhere := options.Spawn == DLASpawnAnywhere
+
+		// Once the edges have all turned into floor, particles can't start from them anymore, so they start from any of the cells
+		// that are left instead.
+		if !anywhere && filled(minX, minY, maxX, minY) && filled(minX, maxY, maxX, maxY) && filled(minX, minY, minX, maxY) && filled(maxX, minY, maxX, maxY) {
+			anywhere = true
+		}
+
+		if anywhere && filled(minX, minY, maxX, maxY) {
+			return Position{}, fmt.Errorf("dngn: GenerateDLA: no room left to fill %v of the layout: %w", options.PercentageFilled, ErrCannotConverge)
+		}
+
+		for {
+
+			var particle Position
+
+			if anywhere {
+				particle = Position{minX + layout.RNG.Intn(maxX-minX+1), minY + layout.RNG.Intn(maxY-minY+1)}
+			} else {
+				// Pick a position along the border of the walkable area, with each edge weighted by its length.
+				w, h := maxX-minX+1, maxY-minY+1
+				i := layout.RNG.Intn(w*2 + h*2)
+				switch {
+				case i < w:
+					particle = Position{minX + i, minY}
+				case i < w*2:
+					particle = Position{minX + i - w, maxY}
+				case i < w*2+h:
+					particle = Position{minX, minY + i - w*2}
+				default:
+					particle = Position{maxX, minY + i - w*2 - h}
+				}
+			}
+
+			if layout.Get(particle.X, particle.Y) != options.FloorValue {
+				return particle, nil
+			}
+
+			if err := gen.step(GenerationStep{Kind: StepWalk, X: particle.X, Y: particle.Y, W: 1, H: 1}); err != nil {
+				return particle, err
+			}
+
+		}
+
+	}
+
+	for fillCount/totalArea < options.PercentageFilled {
+
+		particle, err := spawn()
+		if err != nil {
+			return nil, err
+		}
+
+		// Particles that wander for too long without touching the floor are dropped, and a new one is spawned in their place.
+		steps := 0
+
+		for !touchesFloor(particle.X, particle.Y) && steps < dlaParticleSteps*layout.Area() {
+
+			steps++
+
+			if options.Attraction > 0 && layout.RNG.Float32() < options.Attraction {
+				target := nearestSeed(particle)
+				dx, dy := target.X-particle.X, target.Y-particle.Y
+				if dx*dx >= dy*dy {
+					particle.X += sign(dx)
+				} else {
+					particle.Y += sign(dy)
+				}
+			} else {
+				dir := directions[layout.RNG.Intn(4)]
+				particle.X = clampInt(particle.X+dir.X, minX, maxX)
+				particle.Y = clampInt(particle.Y+dir.Y, minY, maxY)
+			}
+
+			if err := gen.step(GenerationStep{Kind: StepWalk, X: particle.X, Y: particle.Y, W: 1, H: 1}); err != nil {
+				return nil, err
+			}
+
+		}
+
+		if !touchesFloor(particle.X, particle.Y) {
+			continue
+		}
+
+		if err := stick(particle.X, particle.Y); err != nil {
+			return nil, err
+		}
+
+	}
+
+	return layout.regionGraph(options.FloorValue), nil
+
+}
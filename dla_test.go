@@ -0,0 +1,84 @@
+package dngn
+
+import (
+	"context"
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+	"time"
+)
+
+// TestDLAFull checks that DLA can fill most of the Layout, even after the cells along the spawn edges have all turned into floor.
+func TestDLAFull(t *testing.T) {
+
+	for _, spawn := range []DLASpawn{DLASpawnEdges, DLASpawnAnywhere} {
+
+		layout := NewLayout(30, 20)
+		layout.RNG = rand.New(rand.NewSource(1))
+
+		options := NewDefaultDLAOptions()
+		options.PercentageFilled = 0.8
+		options.Padding = 1
+		options.Spawn = spawn
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+
+		if _, err := layout.GenerateDLAContext(ctx, options); err != nil {
+			t.Errorf("spawn %d: %v", spawn, err)
+		}
+
+		cancel()
+
+	}
+
+}
+
+// TestDLAAttraction checks that particles pulled towards a seed away from the center still stick, rather than swinging back and
+// forth forever.
+func TestDLAAttraction(t *testing.T) {
+
+	layout := NewLayout(30, 20)
+	layout.RNG = rand.New(rand.NewSource(1))
+
+	options := NewDefaultDLAOptions()
+	options.Attraction = 1
+	options.Seeds = []Position{{2, 2}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if _, err := layout.GenerateDLAContext(ctx, options); err != nil {
+		t.Fatal(err)
+	}
+
+	if layout.Get(2, 2) != options.FloorValue {
+		t.Errorf("the seed isn't floor")
+	}
+
+}
+
+// TestDLAInvalid checks that percentages and attractions outside of 0 - 1 (including NaN) are rejected.
+func TestDLAInvalid(t *testing.T) {
+
+	nan := float32(math.NaN())
+
+	for name, set := range map[string]func(options *DLAOptions){
+		"percentage filled of NaN": func(options *DLAOptions) { options.PercentageFilled = nan },
+		"attraction of NaN":        func(options *DLAOptions) { options.Attraction = nan },
+		"attraction of 2":          func(options *DLAOptions) { options.Attraction = 2 },
+	} {
+
+		layout := NewLayout(30, 20)
+		layout.RNG = rand.New(rand.NewSource(1))
+
+		options := NewDefaultDLAOptions()
+		set(&options)
+
+		if _, err := layout.GenerateDLA(options); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("%s: expected ErrInvalidArgument, got %v", name, err)
+		}
+
+	}
+
+}
@@ -7,8 +7,9 @@ content on the map.
 package dngn
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"image"
 	"math"
 	"math/rand"
 	"sort"
@@ -20,10 +21,131 @@ import (
 // Data is the core underlying data structure representing the dungeon. It's a 2D array of runes.
 // RNG is the random number generator of the Layout to use when doing random generation using the Generate* functions below. By default,
 // the a generator is made at runtime.
+// OnStep, if set, is called by the Generate* functions after each step they take (each split, door, room, corridor, or walk step),
+// while the Layout's Data reflects the generation up to that point. This can be used to animate generation or inspect it
+// step-by-step (see Layout.Clone() to keep a copy of each step).
+// StepBudget is the maximum number of steps a single call to one of the Generate*Context functions can take before giving up and
+// returning an error wrapping ErrStepBudgetExceeded. A StepBudget of 0 means there's no limit.
+// Grid is the shape of the Layout's cells (square by default, or one of the hex grids), which decides which cells neighbor each other
+// for the functions that walk from cell to cell (see GridType and Layout.Neighbors()).
 type Layout struct {
 	Width, Height int
 	Data          [][]rune
 	RNG           *rand.Rand
+	OnStep        func(step GenerationStep)
+	StepBudget    int
+	Grid          GridType
+}
+
+// StepKind indicates what kind of step a generator took when it calls Layout.OnStep.
+type StepKind int
+
+const (
+	StepSplit         StepKind = iota // GenerateBSP split a room in two.
+	StepSplitRejected                 // GenerateBSP tried to split a room, but didn't, as the split wasn't valid.
+	StepDoor                          // A door was placed.
+	StepRoom                          // A room was placed.
+	StepCorridor                      // A corridor was drawn between two points.
+	StepCarve                         // A walker carved out a new cell, or a particle stuck to the floor.
+	StepWalk                          // A walker moved over a cell that was already carved out, or a particle moved.
+	StepFill                          // An area of the Layout was filled in, like a row of cells sampled from noise.
+)
+
+// String returns the name of the StepKind.
+func (kind StepKind) String() string {
+	switch kind {
+	case StepSplit:
+		return "Split"
+	case StepSplitRejected:
+		return "SplitRejected"
+	case StepDoor:
+		return "Door"
+	case StepRoom:
+		return "Room"
+	case StepCorridor:
+		return "Corridor"
+	case StepCarve:
+		return "Carve"
+	case StepWalk:
+		return "Walk"
+	case StepFill:
+		return "Fill"
+	}
+	return fmt.Sprintf("StepKind(%d)", int(kind))
+}
+
+// GenerationStep describes a single step taken by one of the Generate* functions; it's passed to Layout.OnStep.
+// Generator is the name of the generator ("bsp", "rooms", "drunk", "cyclic", "dla", "noise", "biomes", "town", "chunks", or "cellular"), and Kind is what was done in the step. X, Y, W, and H are the
+// area of the Layout the step affected; for splits, this is the area of the room being split, while for corridors, it's the start (X, Y)
+// and end (X+W, Y+H) of the line. For splits, Vertical is the axis of the split, and SplitPosition is the X or Y position of the dividing
+// line. Room is the room involved in the step, if there is one.
+type GenerationStep struct {
+	Generator     string
+	Kind          StepKind
+	X, Y, W, H    int
+	Vertical      bool
+	SplitPosition int
+	Room          *Room
+}
+
+// ErrInvalidArgument is returned (wrapped) when a function is given arguments or options it can't work with. The error's message
+// describes the problem.
+var ErrInvalidArgument = errors.New("invalid argument")
+
+// invalidArgument returns an error wrapping ErrInvalidArgument describing the problem with the arguments given to the function named.
+func invalidArgument(function string, format string, args ...interface{}) error {
+	return fmt.Errorf("dngn: %s: %s: %w", function, fmt.Sprintf(format, args...), ErrInvalidArgument)
+}
+
+// validateSize returns an error if the Layout has no cells to generate a map in.
+func (layout *Layout) validateSize(function string) error {
+	if layout.Width <= 0 || layout.Height <= 0 {
+		return invalidArgument(function, "layout size must be positive, got %dx%d", layout.Width, layout.Height)
+	}
+	return nil
+}
+
+// ErrStepBudgetExceeded is returned (wrapped) by the Generate*Context functions when generation takes more steps than the Layout's
+// StepBudget allows.
+var ErrStepBudgetExceeded = errors.New("step budget exceeded")
+
+// ErrCannotConverge is returned (wrapped) by the Generate*Context functions when the arguments given would make generation run forever.
+var ErrCannotConverge = errors.New("generation can't converge")
+
+// generation tracks a single call to one of the Layout's Generate functions.
+type generation struct {
+	layout *Layout
+	ctx    context.Context
+	name   string
+	steps  int
+}
+
+// generation starts tracking a call to the named Generate function.
+func (layout *Layout) generation(ctx context.Context, name string) *generation {
+	return &generation{layout: layout, ctx: ctx, name: name}
+}
+
+// step reports the step provided to the Layout's OnStep function, if it's set. It returns an error if the generation's context was
+// cancelled or the Layout's StepBudget has been used up, in which case the generator should stop and return the error.
+func (gen *generation) step(step GenerationStep) error {
+
+	step.Generator = gen.name
+	gen.steps++
+
+	if gen.layout.OnStep != nil {
+		gen.layout.OnStep(step)
+	}
+
+	if err := gen.ctx.Err(); err != nil {
+		return fmt.Errorf("dngn: %s generation stopped after %d steps: %w", gen.name, gen.steps, err)
+	}
+
+	if gen.layout.StepBudget > 0 && gen.steps > gen.layout.StepBudget {
+		return fmt.Errorf("dngn: %s generation stopped after %d steps: %w", gen.name, gen.steps, ErrStepBudgetExceeded)
+	}
+
+	return nil
+
 }
 
 // NewLayout returns a new Layout with the specified width and height.
@@ -41,8 +163,20 @@ func NewLayout(width, height int) *Layout {
 	return r
 }
 
-// NewLayoutFromRuneArrays creates a new Layout with the data contained in the provided rune arrays.
-func NewLayoutFromRuneArrays(arrays [][]rune) *Layout {
+// NewLayoutFromRuneArrays creates a new Layout with the data contained in the provided rune arrays. An error is returned if there's
+// no data, or if the arrays aren't all the same length.
+func NewLayoutFromRuneArrays(arrays [][]rune) (*Layout, error) {
+
+	if len(arrays) == 0 || len(arrays[0]) == 0 {
+		return nil, invalidArgument("NewLayoutFromRuneArrays", "no data given")
+	}
+
+	for y, array := range arrays {
+		if len(array) != len(arrays[0]) {
+			return nil, invalidArgument("NewLayoutFromRuneArrays", "row %d has %d runes, but row 0 has %d", y, len(array), len(arrays[0]))
+		}
+	}
+
 	r := &Layout{Width: len(arrays[0]), Height: len(arrays)}
 	r.RNG = rand.New(rand.NewSource(rand.Int63()))
 	r.Data = [][]rune{}
@@ -53,11 +187,12 @@ func NewLayoutFromRuneArrays(arrays [][]rune) *Layout {
 		}
 	}
 
-	return r
+	return r, nil
 }
 
-// NewLayoutFromStringArray creates a new Map with the data contained in the provided string array.
-func NewLayoutFromStringArray(array []string) *Layout {
+// NewLayoutFromStringArray creates a new Map with the data contained in the provided string array. An error is returned if there's
+// no data, or if the strings aren't all the same length (in runes).
+func NewLayoutFromStringArray(array []string) (*Layout, error) {
 	runes := [][]rune{}
 
 	for _, str := range array {
@@ -73,16 +208,43 @@ func (layout *Layout) SetRNG(source rand.Source) {
 }
 
 // GenerateBSP generates a map in the given Layout using BSP (binary space partitioning) generation, drawing lines of WallValue runes horizontally and
-// vertically across, partitioning the room into pieces. It also will place single cells of doorValue on the walls, creating
-// doorways. Link: http://www.roguebasin.com/index.php?title=Basic_BSP_Dungeon_generation
+// vertically across, partitioning the room into pieces. It also will place doors of DoorValue on the walls between rooms, creating
+// doorways, according to the options' door policy (see BSPDoorPolicy). Link: http://www.roguebasin.com/index.php?title=Basic_BSP_Dungeon_generation
 // GenerateBSP works best with an empty Layout.
-// GenerateBSP returns a list of rooms generated through this method; these are simply internal data structures used to tell
-// where rooms start and stop, and can be used in accordance with Layout.Set() or Layout.Select() to modify these rooms. For BSP
-// Generation, walls are always on the X and Y lines of the Layout only; not the right or bottom sides.
-func (layout *Layout) GenerateBSP(bspOptions BSPOptions) []*BSPRoom {
-	layout.Select().Fill(' ')
+// GenerateBSP returns a BSPResult containing the list of rooms generated through this method, the doors between them, and the partition
+// tree that was built to make them; the rooms are simply internal data structures used to tell where rooms start and stop, and can be
+// used in accordance with Layout.Set() or Layout.Select() to modify these rooms. For BSP Generation, walls are always on the X and Y lines of the Layout
+// only; not the right or bottom sides.
+// If bspOptions.EnsureConnected is true, every room is guaranteed to be reachable from every other room.
+// If bspOptions.CarveRooms is true, GenerateBSP instead fills the Layout with walls, carves a randomly sized room inside each partition,
+// and connects the rooms with corridors (see BSPOptions).
+// An error is returned if the options are invalid. GenerateBSP is the same as GenerateBSPContext() with a background context.
+func (layout *Layout) GenerateBSP(bspOptions BSPOptions) (*BSPResult, error) {
+	return layout.GenerateBSPContext(context.Background(), bspOptions)
+}
+
+// GenerateBSPContext works like GenerateBSP(), but stops and returns an error if the context is cancelled or the Layout's
+// StepBudget runs out before generation finishes. Splitting stops when SplitCount splits have been made, or when none of the rooms
+// can be split any further.
+func (layout *Layout) GenerateBSPContext(ctx context.Context, bspOptions BSPOptions) (*BSPResult, error) {
 
-	subSplit := func(parent *BSPRoom) (*BSPRoom, *BSPRoom, bool) {
+	if err := layout.validateSize("GenerateBSP"); err != nil {
+		return nil, err
+	}
+
+	if err := bspOptions.validate(); err != nil {
+		return nil, err
+	}
+
+	if bspOptions.CarveRooms {
+		layout.Select().Fill(bspOptions.WallValue)
+	} else {
+		layout.Select().Fill(' ')
+	}
+
+	gen := layout.generation(ctx, "bsp")
+
+	subSplit := func(parent *Room) (*Room, *Room, bool, error) {
 
 		vertical := layout.RNG.Float32() >= 0.5
 		if parent.W > parent.H*2 {
@@ -97,197 +259,192 @@ func (layout *Layout) GenerateBSP(bspOptions BSPOptions) []*BSPRoom {
 
 			splitCX := int(float32(parent.W) * splitPercentage)
 
-			a := NewBSPRoom(parent.X, parent.Y, splitCX, parent.H)
-			b := NewBSPRoom(parent.X+splitCX, parent.Y, parent.W-splitCX, parent.H)
+			a := NewRoom(parent.X, parent.Y, splitCX, parent.H)
+			b := NewRoom(parent.X+splitCX, parent.Y, parent.W-splitCX, parent.H)
 
 			if a.MinSize() <= bspOptions.MinimumRoomSize || b.MinSize() <= bspOptions.MinimumRoomSize {
-				return a, b, false
+				return a, b, false, gen.step(splitStep(StepSplitRejected, parent, true, parent.X+splitCX))
 			}
 
 			// Line is attempting to start on a door
 			if bspOptions.DoorValue != bspOptions.WallValue && bspOptions.DoorValue != 0 && (layout.Get(parent.X+splitCX, parent.Y) == bspOptions.DoorValue || layout.Get(parent.X+splitCX, parent.Y+parent.H) == bspOptions.DoorValue) {
-				return a, b, false
+				return a, b, false, gen.step(splitStep(StepSplitRejected, parent, true, parent.X+splitCX))
 			}
 
-			layout.DrawLine(parent.X+splitCX, parent.Y, parent.X+splitCX, parent.Y+parent.H-1, bspOptions.WallValue, 1, false)
+			if !bspOptions.CarveRooms {
+				layout.DrawLine(parent.X+splitCX, parent.Y, parent.X+splitCX, parent.Y+parent.H-1, bspOptions.WallValue, 1, false)
+			}
 
-			return a, b, true
+			return a, b, true, gen.step(splitStep(StepSplit, parent, true, parent.X+splitCX))
 		}
 
 		splitCY := int(float32(parent.H) * splitPercentage)
 
-		a := NewBSPRoom(parent.X, parent.Y, parent.W, splitCY)
-		b := NewBSPRoom(parent.X, parent.Y+splitCY, parent.W, parent.H-splitCY)
+		a := NewRoom(parent.X, parent.Y, parent.W, splitCY)
+		b := NewRoom(parent.X, parent.Y+splitCY, parent.W, parent.H-splitCY)
 
 		// We can't split a room too small.
 		if a.MinSize() <= bspOptions.MinimumRoomSize || b.MinSize() <= bspOptions.MinimumRoomSize {
-			return a, b, false
+			return a, b, false, gen.step(splitStep(StepSplitRejected, parent, false, parent.Y+splitCY))
 		}
 
 		// Line is attempting to start on a door
 		if bspOptions.DoorValue != bspOptions.WallValue && bspOptions.DoorValue != 0 && (layout.Get(parent.X, parent.Y+splitCY) == bspOptions.DoorValue || layout.Get(parent.X+parent.W, parent.Y+splitCY) == bspOptions.DoorValue) {
-			return a, b, false
+			return a, b, false, gen.step(splitStep(StepSplitRejected, parent, false, parent.Y+splitCY))
 		}
 
-		layout.DrawLine(parent.X, parent.Y+splitCY, parent.X+parent.W-1, parent.Y+splitCY, bspOptions.WallValue, 1, false)
+		if !bspOptions.CarveRooms {
+			layout.DrawLine(parent.X, parent.Y+splitCY, parent.X+parent.W-1, parent.Y+splitCY, bspOptions.WallValue, 1, false)
+		}
 
-		return a, b, true
+		return a, b, true, gen.step(splitStep(StepSplit, parent, false, parent.Y+splitCY))
 
 	}
 
-	rooms := []*BSPRoom{
-		NewBSPRoom(0, 0, layout.Width, layout.Height),
+	rooms := []*Room{
+		NewRoom(0, 0, layout.Width, layout.Height),
 	}
 
+	// The node in the partition tree for each of the rooms (partitions) that haven't been split.
+	tree := &BSPNode{X: 0, Y: 0, W: layout.Width, H: layout.Height}
+	nodes := map[*Room]*BSPNode{rooms[0]: tree}
+
+	// Rooms that failed to split bspSplitAttempts times in a row are considered finished, and aren't picked to be split again.
+	failedSplits := map[*Room]int{}
+
 	splitCount := 0
 
-	i := 0
-	for {
+	for splitCount < bspOptions.SplitCount {
+
+		candidates := []*Room{}
+
+		for _, room := range rooms {
+			if failedSplits[room] < bspSplitAttempts {
+				candidates = append(candidates, room)
+			}
+		}
+
+		// None of the rooms can be split any further, so we're done.
+		if len(candidates) == 0 {
+			break
+		}
+
 		// Sort the rooms so bigger ones can be prioritized sometimes
-		sort.Slice(rooms, func(i, j int) bool {
-			// return rooms[i].Area() > rooms[j].Area()
-			return rooms[i].MinSize() > rooms[j].MinSize()
+		sort.Slice(candidates, func(i, j int) bool {
+			// return candidates[i].Area() > candidates[j].Area()
+			return candidates[i].MinSize() > candidates[j].MinSize()
 		})
 
-		splitChoice := rooms[layout.RNG.Intn(len(rooms))]
+		splitChoice := candidates[layout.RNG.Intn(len(candidates))]
 
 		if layout.RNG.Float32() >= 0.2 {
-			splitChoice = rooms[0] // Try to split the biggest rooms first
+			splitChoice = candidates[0] // Try to split the biggest rooms first
 		}
 
 		// Do the split
-		a, b, success := subSplit(splitChoice)
-
-		i++
+		a, b, success, err := subSplit(splitChoice)
 
-		if i >= bspOptions.SplitCount*10 { // Assume it's done to avoid just hanging the system
-			break
+		if err != nil {
+			return nil, err
 		}
 
 		if !success {
+			failedSplits[splitChoice]++
 			continue
-		} else {
+		}
 
-			rooms = append(rooms, a, b)
+		rooms = append(rooms, a, b)
 
-			for i, r := range rooms {
-				if r == splitChoice {
-					rooms = append(rooms[:i], rooms[i+1:]...)
-					break
-				}
-			}
+		node := nodes[splitChoice]
+		node.Vertical = a.H == node.H
+		node.SplitPosition = b.Y
+		if node.Vertical {
+			node.SplitPosition = b.X
+		}
+		node.Left = &BSPNode{X: a.X, Y: a.Y, W: a.W, H: a.H, Parent: node}
+		node.Right = &BSPNode{X: b.X, Y: b.Y, W: b.W, H: b.H, Parent: node}
+		nodes[a] = node.Left
+		nodes[b] = node.Right
+		delete(nodes, splitChoice)
 
+		for i, r := range rooms {
+			if r == splitChoice {
+				rooms = append(rooms[:i], rooms[i+1:]...)
+				break
+			}
 		}
 
 		splitCount++
 
-		if splitCount >= bspOptions.SplitCount {
-			break
-		}
-
 	}
 
-	// Generate Doors
-	for _, subroom := range rooms {
-
-		possibleExits := []Position{}
-
-		spawnOptions := []int{0, 1, 2}
+	result := &BSPResult{Tree: tree}
 
-		spawnChoice := spawnOptions[layout.RNG.Intn(len(spawnOptions))]
+	if bspOptions.CarveRooms {
 
-		// Rooms on the border must generate a doorway that works for them
-		if subroom.X == 0 || subroom.Y == 0 {
-			spawnChoice = 2
+		if err := layout.carveBSPRooms(gen, result, bspOptions); err != nil {
+			return nil, err
 		}
 
-		// Spawn both directions; Y-axis
-		if spawnChoice == 1 || spawnChoice == 2 {
-
-			if subroom.Y > 0 {
-
-				for x := subroom.X; x < subroom.X+subroom.W; x++ {
-
-					up := layout.Get(x, subroom.Y-1)
-					down := layout.Get(x, subroom.Y+1)
-
-					if up == ' ' && down == ' ' {
-						possibleExits = append(possibleExits, Position{x, subroom.Y})
-					}
-
-				}
-
-				doorway := possibleExits[layout.RNG.Intn(len(possibleExits))]
-
-				layout.Set(doorway.X, doorway.Y, bspOptions.DoorValue)
-
-				doorRect := image.Rect(doorway.X, doorway.Y-1, doorway.X+1, doorway.Y)
-
-				for _, other := range rooms {
-
-					otherRect := image.Rect(other.X, other.Y, other.X+other.W, other.Y+other.H)
-
-					if otherRect.Overlaps(doorRect) {
-						other.Connected = append(other.Connected, subroom)
-						subroom.Connected = append(subroom.Connected, other)
-					}
-
-				}
-
-			}
+	} else {
 
+		for _, room := range rooms {
+			nodes[room].Room = room
 		}
 
-		possibleExits = []Position{}
-
-		// X-axis doorways
-		if spawnChoice == 0 || spawnChoice == 2 {
-
-			if subroom.X > 0 {
-
-				for y := subroom.Y; y < subroom.Y+subroom.H; y++ {
+		result.Rooms = rooms
 
-					left := layout.Get(subroom.X-1, y)
-					right := layout.Get(subroom.X+1, y)
-
-					if left == ' ' && right == ' ' {
-						possibleExits = append(possibleExits, Position{subroom.X, y})
-					}
-
-				}
+		if err := layout.placeBSPDoors(gen, result, bspOptions); err != nil {
+			return nil, err
+		}
 
-				doorway := possibleExits[layout.RNG.Intn(len(possibleExits))]
+	}
 
-				layout.Set(doorway.X, doorway.Y, bspOptions.DoorValue)
+	for _, room := range result.Rooms {
+		room.Cells = layout.areaCells(room.X, room.Y, room.W, room.H, ' ')
+	}
 
-				for _, other := range rooms {
+	return result, nil
 
-					otherRect := image.Rect(other.X, other.Y, other.X+other.W, other.Y+other.H)
-					if otherRect.Overlaps(image.Rect(doorway.X-1, doorway.Y, doorway.X, doorway.Y+1)) {
-						other.Connected = append(other.Connected, subroom)
-						subroom.Connected = append(subroom.Connected, other)
-					}
+}
 
-				}
+// GenerateRandomRooms generates a map using random room creation. emptyRune is the rune to fill the rooms generated with, while wallRune is the rune to use as walls (unwalkable tiles).
+// roomCount is how many rooms to place, roomMinWidth and Height are how small they can be, minimum, while roomMaxWidth and Height are how large
+// they can be. connectRooms determines if the algorithm should also attempt to connect the rooms using pathways between each room. The
+// function returns a RoomGraph containing the rooms created, or an error if the room count or sizes are invalid. Each room's Cells are
+// the floor cells in its rectangle; rooms can overlap each other. Rooms are connected if they overlap or touch, or if a pathway runs
+// between them.
+// GenerateRandomRooms is the same as GenerateRandomRoomsContext() with a background context.
+func (layout *Layout) GenerateRandomRooms(emptyRune rune, wallRune rune, roomCount, roomMinWidth, roomMinHeight, roomMaxWidth, roomMaxHeight int, connectRooms bool) (*RoomGraph, error) {
+	return layout.GenerateRandomRoomsContext(context.Background(), emptyRune, wallRune, roomCount, roomMinWidth, roomMinHeight, roomMaxWidth, roomMaxHeight, connectRooms)
+}
 
-			}
+// GenerateRandomRoomsContext works like GenerateRandomRooms(), but stops and returns an error if the context is cancelled or the
+// Layout's StepBudget runs out before generation finishes.
+func (layout *Layout) GenerateRandomRoomsContext(ctx context.Context, emptyRune rune, wallRune rune, roomCount, roomMinWidth, roomMinHeight, roomMaxWidth, roomMaxHeight int, connectRooms bool) (*RoomGraph, error) {
 
-		}
+	if err := layout.validateSize("GenerateRandomRooms"); err != nil {
+		return nil, err
+	}
 
+	if roomCount < 0 {
+		return nil, invalidArgument("GenerateRandomRooms", "roomCount can't be negative, got %d", roomCount)
 	}
 
-	return rooms
+	if roomMinWidth <= 0 || roomMinHeight <= 0 {
+		return nil, invalidArgument("GenerateRandomRooms", "minimum room size must be positive, got %dx%d", roomMinWidth, roomMinHeight)
+	}
 
-}
+	if roomMaxWidth < roomMinWidth || roomMaxHeight < roomMinHeight {
+		return nil, invalidArgument("GenerateRandomRooms", "maximum room size (%dx%d) can't be smaller than the minimum room size (%dx%d)", roomMaxWidth, roomMaxHeight, roomMinWidth, roomMinHeight)
+	}
 
-// GenerateRandomRooms generates a map using random room creation. emptyRune is the rune to fill the rooms generated with, while wallRune is the rune to use as walls (unwalkable tiles).
-// roomCount is how many rooms to place, roomMinWidth and Height are how small they can be, minimum, while roomMaxWidth and Height are how large
-// they can be. connectRooms determines if the algorithm should also attempt to connect the rooms using pathways between each room. The
-// function returns the positions of each room created.
-func (layout *Layout) GenerateRandomRooms(emptyRune rune, wallRune rune, roomCount, roomMinWidth, roomMinHeight, roomMaxWidth, roomMaxHeight int, connectRooms bool) [][]int {
 	layout.Select().Fill(wallRune)
 
+	gen := layout.generation(ctx, "rooms")
+
 	roomPositions := make([][]int, 0)
+	graph := &RoomGraph{Rooms: []*Room{}, Doors: []Door{}}
 
 	for i := 0; i < roomCount; i++ {
 
@@ -298,8 +455,15 @@ func (layout *Layout) GenerateRandomRooms(emptyRune rune, wallRune rune, roomCou
 
 		roomPositions = append(roomPositions, []int{sx, sy})
 
-		roomW := roomMinWidth + layout.RNG.Intn(roomMaxWidth-roomMinWidth)
-		roomH := roomMinHeight + layout.RNG.Intn(roomMaxHeight-roomMinHeight)
+		roomW := roomMinWidth
+		if roomMaxWidth > roomMinWidth {
+			roomW += layout.RNG.Intn(roomMaxWidth - roomMinWidth)
+		}
+
+		roomH := roomMinHeight
+		if roomMaxHeight > roomMinHeight {
+			roomH += layout.RNG.Intn(roomMaxHeight - roomMinHeight)
+		}
 
 		drawRoom := func(x, y int) bool {
 			dx := int(math.Abs(float64(sx) - float64(x)))
@@ -312,6 +476,19 @@ func (layout *Layout) GenerateRandomRooms(emptyRune rune, wallRune rune, roomCou
 
 		layout.Select().FilterBy(drawRoom)
 
+		// The room's bounds are clamped to the Layout.
+		x, y := sx-roomW+1, sy-roomH+1
+		x2, y2 := x+roomW*2-1, y+roomH*2-1
+		x, y = clampInt(x, 0, layout.Width), clampInt(y, 0, layout.Height)
+		x2, y2 = clampInt(x2, 0, layout.Width), clampInt(y2, 0, layout.Height)
+
+		room := NewRoom(x, y, x2-x, y2-y)
+		graph.Rooms = append(graph.Rooms, room)
+
+		if err := gen.step(GenerationStep{Kind: StepRoom, X: sx - roomW + 1, Y: sy - roomH + 1, W: roomW*2 - 1, H: roomH*2 - 1, Room: room}); err != nil {
+			return graph, err
+		}
+
 	}
 
 	if connectRooms {
@@ -328,13 +505,23 @@ func (layout *Layout) GenerateRandomRooms(emptyRune rune, wallRune rune, roomCou
 
 				layout.DrawLine(x, y, x2, y2, emptyRune, 1, true)
 
+				if err := gen.step(GenerationStep{Kind: StepCorridor, X: x, Y: y, W: x2 - x, H: y2 - y}); err != nil {
+					return graph, err
+				}
+
 			}
 
 		}
 
 	}
 
-	return roomPositions
+	for _, room := range graph.Rooms {
+		room.Cells = layout.areaCells(room.X, room.Y, room.W, room.H, emptyRune)
+	}
+
+	layout.connectRoomsThroughFloor(graph.Rooms, emptyRune)
+
+	return graph, nil
 
 }
 
@@ -343,58 +530,52 @@ func (layout *Layout) GenerateRandomRooms(emptyRune rune, wallRune rune, roomCou
 // is filled. Note that it only counts values placed in the cell, not instances where it moves over a cell that already has the
 // value being placed. This can be used to generate maps more similar to simple natural cave systems, as an imaginary example.
 // Link: http://www.roguebasin.com/index.php?title=Random_Walk_Cave_Generation
-func (layout *Layout) GenerateDrunkWalk(emptyRune rune, wallRune rune, percentageFilled float32) {
-	layout.Select().Fill(wallRune)
-
-	sx := layout.RNG.Intn(layout.Width)
-	sy := layout.RNG.Intn(layout.Height)
-	fillCount := float32(0)
-
-	totalArea := float32(layout.Area())
+// GenerateDrunkWalk returns a RoomGraph containing a Room for each separate cave (contiguous area of emptyRune; see Layout.Regions()).
+// As the caves aren't connected to each other, the rooms have no connections or doors.
+// For more walkers, tunnels, and other variations, use GenerateDrunkWalkWithOptions().
+// An error is returned if percentageFilled isn't between 0 and 1. GenerateDrunkWalk is the same as GenerateDrunkWalkContext() with a
+// background context.
+func (layout *Layout) GenerateDrunkWalk(emptyRune rune, wallRune rune, percentageFilled float32) (*RoomGraph, error) {
+	return layout.GenerateDrunkWalkContext(context.Background(), emptyRune, wallRune, percentageFilled)
+}
 
-	for {
-		cell := layout.Get(sx, sy)
+// GenerateDrunkWalkContext works like GenerateDrunkWalk(), but stops and returns an error if the context is cancelled or the Layout's
+// StepBudget runs out before generation finishes. If percentageFilled isn't between 0 and 1, the walk could never finish, so an error
+// wrapping ErrInvalidArgument is returned without generating anything.
+func (layout *Layout) GenerateDrunkWalkContext(ctx context.Context, emptyRune rune, wallRune rune, percentageFilled float32) (*RoomGraph, error) {
 
-		if cell != emptyRune {
-			layout.Set(sx, sy, emptyRune)
-			fillCount++
-		}
+	options := NewDefaultDrunkWalkOptions()
+	options.FloorValue = emptyRune
+	options.WallValue = wallRune
+	options.PercentageFilled = percentageFilled
 
-		dir := layout.RNG.Intn(4)
-		switch dir {
-		case 0:
-			sx++
-		case 1:
-			sx--
-		case 2:
-			sy++
-		case 3:
-			sy--
-		}
+	return layout.GenerateDrunkWalkWithOptionsContext(ctx, options)
 
-		if sx < 0 {
-			sx = 0
-		} else if sx >= layout.Width {
-			sx = layout.Width - 1
-		}
+}
 
-		if sy < 0 {
-			sy = 0
-		} else if sy >= layout.Height {
-			sy = layout.Height - 1
-		}
+// Clone returns a copy of the Layout, with its own copy of the Layout's Data. The clone shares the original Layout's RNG, OnStep
+// function, StepBudget, and Grid.
+func (layout *Layout) Clone() *Layout {
 
-		if fillCount/totalArea >= percentageFilled {
-			break
-		}
+	clone := &Layout{Width: layout.Width, Height: layout.Height, RNG: layout.RNG, OnStep: layout.OnStep, StepBudget: layout.StepBudget, Grid: layout.Grid}
+	clone.Data = make([][]rune, len(layout.Data))
 
+	for y := range layout.Data {
+		clone.Data[y] = append([]rune{}, layout.Data[y]...)
 	}
 
+	return clone
+
 }
 
-// Rotate rotates the entire room 90 degrees clockwise.
+// Rotate rotates the entire room 90 degrees clockwise. Rotating a hex grid moves its cells around as if they were square, so the
+// result won't look like the original turned on its side.
 func (layout *Layout) Rotate() {
 
+	if layout.Width <= 0 || layout.Height <= 0 {
+		return
+	}
+
 	newData := make([][]rune, 0)
 
 	for y := 0; y < len(layout.Data[0]); y++ {
@@ -602,11 +783,11 @@ func (layout *Layout) DataToString() string {
 
 	s := fmt.Sprintf("  W:%d H:%d\n\n       ", layout.Width, layout.Height)
 
-	for y := 1; y < len(layout.Data[0]); y += 2 {
+	for y := 1; y < layout.Width; y += 2 {
 		s += fmt.Sprintf("%2d  ", y)
 	}
 	s += "\n     "
-	for y := 0; y < len(layout.Data[0]); y += 2 {
+	for y := 0; y < layout.Width; y += 2 {
 		s += fmt.Sprintf("%2d  ", y)
 	}
 
@@ -634,7 +815,8 @@ func (layout *Layout) Select() Selection {
 	return newSelection.All()
 }
 
-// SelectContiguous creates a Selection from all cells contiguous to the cell in the (x,y) position provided.
+// SelectContiguous creates a Selection from all cells contiguous to the cell in the (x,y) position provided. Cells are contiguous if
+// they neighbor each other according to the Layout's Grid (see Layout.Neighbors()); diagonal only applies to square grids.
 func (layout *Layout) SelectContiguous(x, y int, diagonal bool) Selection {
 
 	toAdd := []Position{
@@ -653,23 +835,7 @@ func (layout *Layout) SelectContiguous(x, y int, diagonal bool) Selection {
 
 		added[position] = true
 
-		sides := []Position{
-			{position.X - 1, position.Y},
-			{position.X + 1, position.Y},
-			{position.X, position.Y - 1},
-			{position.X, position.Y + 1},
-		}
-
-		if diagonal {
-			sides = append(sides,
-				Position{position.X - 1, position.Y - 1},
-				Position{position.X + 1, position.Y + 1},
-				Position{position.X + 1, position.Y - 1},
-				Position{position.X - 1, position.Y + 1},
-			)
-		}
-
-		for _, side := range sides {
+		for _, side := range layout.Neighbors(position.X, position.Y, diagonal) {
 
 			if layout.Get(side.X, side.Y) == startingValue && !added[side] {
 				toAdd = append(toAdd, side)
@@ -688,3 +854,126 @@ func (layout *Layout) SelectContiguous(x, y int, diagonal bool) Selection {
 
 	return newSelection
 }
+
+// Regions returns a Selection for each separate contiguous area of cells that have the value provided, ordered by the position of
+// each area's first cell (going from left to right, top to bottom). If diagonal is true, cells touching diagonally are considered
+// to be contiguous.
+func (layout *Layout) Regions(value rune, diagonal bool) []Selection {
+
+	regions := []Selection{}
+	visited := map[Position]bool{}
+
+	for y := 0; y < layout.Height; y++ {
+		for x := 0; x < layout.Width; x++ {
+
+			if layout.Get(x, y) != value || visited[Position{x, y}] {
+				continue
+			}
+
+			region := layout.SelectContiguous(x, y, diagonal)
+
+			for cell := range region.Cells {
+				visited[cell] = true
+			}
+
+			regions = append(regions, region)
+
+		}
+	}
+
+	return regions
+
+}
+
+// ConnectRegions connects all separate contiguous areas of cells with the value provided (as returned by Layout.Regions()) by
+// carving the shortest path between them, one area at a time, using fillRune. Carved paths only move in cardinal directions (or from
+// hex to hex, on hex grids). The function returns the number of paths carved.
+func (layout *Layout) ConnectRegions(value rune, fillRune rune) int {
+
+	regions := layout.Regions(value, false)
+
+	if len(regions) < 2 {
+		return 0
+	}
+
+	regionIndex := map[Position]int{}
+
+	for i, region := range regions {
+		for cell := range region.Cells {
+			regionIndex[cell] = i
+		}
+	}
+
+	// Connected contains every cell that's already reachable from the first region, including carved paths.
+	connected := map[Position]bool{}
+	for cell := range regions[0].Cells {
+		connected[cell] = true
+	}
+
+	connectedRegions := 1
+	carved := 0
+
+	for connectedRegions < len(regions) {
+
+		// Breadth-first search outwards from everything connected so far until we run into another region.
+		toCheck := []Position{}
+		from := map[Position]Position{}
+
+		for y := 0; y < layout.Height; y++ {
+			for x := 0; x < layout.Width; x++ {
+				if connected[Position{x, y}] {
+					toCheck = append(toCheck, Position{x, y})
+				}
+			}
+		}
+
+		found := Position{-1, -1}
+
+		for len(toCheck) > 0 && found.X < 0 {
+
+			next := toCheck[0]
+			toCheck = toCheck[1:]
+
+			for _, side := range layout.Neighbors(next.X, next.Y, false) {
+
+				if side.X < 0 || side.Y < 0 || side.X >= layout.Width || side.Y >= layout.Height || connected[side] {
+					continue
+				}
+
+				if _, exists := from[side]; exists {
+					continue
+				}
+
+				from[side] = next
+
+				if _, isRegion := regionIndex[side]; isRegion {
+					found = side
+					break
+				}
+
+				toCheck = append(toCheck, side)
+
+			}
+
+		}
+
+		// Carve the path back to the connected area, then mark the region we ran into as connected.
+		for cell := from[found]; !connected[cell]; cell = from[cell] {
+			if layout.Get(cell.X, cell.Y) != value {
+				layout.Set(cell.X, cell.Y, fillRune)
+			}
+			connected[cell] = true
+		}
+
+		for cell := range regions[regionIndex[found]].Cells {
+			connected[cell] = true
+		}
+
+		connectedRegions++
+		carved++
+
+	}
+
+	return carved
+
+}
@@ -0,0 +1,131 @@
+package dngn
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+// TestStepBudget checks that a generator that takes exactly StepBudget steps finishes, and one that needs more stops.
+func TestStepBudget(t *testing.T) {
+
+	generate := func(budget int) (int, error) {
+		layout := NewLayout(30, 20)
+		layout.RNG = rand.New(rand.NewSource(1))
+		layout.StepBudget = budget
+		steps := 0
+		layout.OnStep = func(step GenerationStep) { steps++ }
+		_, err := layout.GenerateCellular(NewDefaultCellularOptions())
+		return steps, err
+	}
+
+	steps, err := generate(0)
+	if err != nil {
+		t.Fatalf("unlimited budget: %v", err)
+	}
+
+	if _, err := generate(steps); err != nil {
+		t.Errorf("budget of %d for %d steps: %v", steps, steps, err)
+	}
+
+	if _, err := generate(steps - 1); !errors.Is(err, ErrStepBudgetExceeded) {
+		t.Errorf("budget of %d for %d steps: expected ErrStepBudgetExceeded, got %v", steps-1, steps, err)
+	}
+
+}
+
+// TestOnStep checks that every generator reports its steps to OnStep, named after the generator.
+func TestOnStep(t *testing.T) {
+
+	steps := []struct {
+		generator string
+		step      PipelineStep
+	}{
+		{"bsp", GenerateStep{Generator: "bsp"}},
+		{"rooms", GenerateStep{Generator: "rooms", RoomCount: 4, RoomMinWidth: 3, RoomMinHeight: 3, RoomMaxWidth: 6, RoomMaxHeight: 6}},
+		{"drunk", GenerateStep{Generator: "drunk"}},
+		{"cyclic", GenerateStep{Generator: "cyclic"}},
+		{"dla", GenerateStep{Generator: "dla"}},
+		{"town", GenerateStep{Generator: "town"}},
+		{"chunks", GenerateStep{Generator: "chunks"}},
+		{"cellular", GenerateStep{Generator: "cellular"}},
+		{"noise", NoiseStep{Fields: []NoiseField{{}}}},
+		{"biomes", BiomeStep{}},
+	}
+
+	for _, test := range steps {
+
+		layout := NewLayout(60, 40)
+		layout.RNG = rand.New(rand.NewSource(1))
+
+		count := 0
+		layout.OnStep = func(step GenerationStep) {
+			count++
+			if step.Generator != test.generator {
+				t.Errorf("%s: step reported as coming from %q", test.generator, step.Generator)
+			}
+		}
+
+		if err := test.step.Apply(layout); err != nil {
+			t.Errorf("%s: %v", test.generator, err)
+			continue
+		}
+
+		if count == 0 {
+			t.Errorf("%s: OnStep was never called", test.generator)
+		}
+
+	}
+
+}
+
+// TestInvalidArguments checks that invalid layout data and generator options are rejected with ErrInvalidArgument, rather than
+// panicking or generating something broken.
+func TestInvalidArguments(t *testing.T) {
+
+	if _, err := NewLayoutFromRuneArrays(nil); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("no rune arrays: expected ErrInvalidArgument, got %v", err)
+	}
+
+	if _, err := NewLayoutFromStringArray([]string{"xxx", "x"}); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("uneven rows: expected ErrInvalidArgument, got %v", err)
+	}
+
+	generate := func(width, height int, generate func(layout *Layout) error) error {
+		layout := NewLayout(width, height)
+		layout.RNG = rand.New(rand.NewSource(1))
+		return generate(layout)
+	}
+
+	bspOptions := NewDefaultBSPOptions()
+	bspOptions.SplitCount = -1
+
+	tests := map[string]func(layout *Layout) error{
+		"negative split count": func(layout *Layout) error {
+			_, err := layout.GenerateBSP(bspOptions)
+			return err
+		},
+		"negative room count": func(layout *Layout) error {
+			_, err := layout.GenerateRandomRooms(' ', 'x', -1, 3, 3, 5, 5, true)
+			return err
+		},
+		"maximum room size under the minimum": func(layout *Layout) error {
+			_, err := layout.GenerateRandomRooms(' ', 'x', 4, 5, 5, 3, 3, true)
+			return err
+		},
+	}
+
+	for name, test := range tests {
+		if err := generate(40, 30, test); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("%s: expected ErrInvalidArgument, got %v", name, err)
+		}
+	}
+
+	if err := generate(0, 0, func(layout *Layout) error {
+		_, err := layout.GenerateBSP(NewDefaultBSPOptions())
+		return err
+	}); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("empty layout: expected ErrInvalidArgument, got %v", err)
+	}
+
+}
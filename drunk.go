@@ -0,0 +1,282 @@
+package dngn
+
+import (
+	"context"
+	"fmt"
+)
+
+// DrunkWalkOptions configures Layout.GenerateDrunkWalkWithOptions().
+type DrunkWalkOptions struct {
+	FloorValue       rune    // The rune walkers carve out.
+	WallValue        rune    // The rune the Layout is filled with before walking.
+	PercentageFilled float32 // How much of the Layout (0 - 1) to fill with FloorValue before stopping.
+
+	Walkers        int        // The number of walkers to start with.
+	MaxWalkers     int        // The most walkers that can be walking at once when spawning new ones. If this is less than Walkers, Walkers is used.
+	StartPositions []Position // Where walkers start; walkers without a start position start at a random position.
+
+	Momentum float32 // The chance (0 - 1) each step that a walker keeps going in the direction it last moved, creating long tunnels.
+	BiasX    float32 // How much (-1 - 1) walkers prefer moving right (positive) or left (negative).
+	BiasY    float32 // How much (-1 - 1) walkers prefer moving down (positive) or up (negative).
+
+	SpawnChance float32 // The chance (0 - 1) each step that a walker spawns a new walker at its position.
+	DeathChance float32 // The chance (0 - 1) each step that a walker stops walking. If the last walker dies, it restarts instead.
+	MaxSteps    int     // How many steps a walker takes before restarting; if 0, walkers never restart on their own.
+
+	// If true, walkers that restart (after reaching MaxSteps, or as the last walker dying) start again on a random floor cell, so
+	// that the caves stay connected. Otherwise, they restart at a random position.
+	RestartFromFloor bool
+
+	BrushSize int // The width and height of the square area carved out by each step.
+	Padding   int // How many cells away from the Layout's edges walkers stay, leaving a solid border of walls.
+}
+
+// NewDefaultDrunkWalkOptions returns a new DrunkWalkOptions for a single walker, which behaves the same as Layout.GenerateDrunkWalk().
+func NewDefaultDrunkWalkOptions() DrunkWalkOptions {
+	return DrunkWalkOptions{
+		FloorValue:       ' ',
+		WallValue:        'x',
+		PercentageFilled: 0.5,
+		Walkers:          1,
+		BrushSize:        1,
+	}
+}
+
+// validate returns an error if the options can't be used to generate a map in the Layout provided.
+func (options DrunkWalkOptions) validate(layout *Layout) error {
+
+	// This is written so that NaN fails it too, since the walk would never fill a NaN percentage of the Layout.
+	if !(options.PercentageFilled >= 0 && options.PercentageFilled <= 1) {
+		return invalidArgument("GenerateDrunkWalk", "percentage filled must be between 0 and 1, got %v", options.PercentageFilled)
+	}
+
+	if options.Walkers < 1 {
+		return invalidArgument("GenerateDrunkWalk", "walker count must be at least 1, got %d", options.Walkers)
+	}
+
+	if options.BrushSize < 1 {
+		return invalidArgument("GenerateDrunkWalk", "brush size must be at least 1, got %d", options.BrushSize)
+	}
+
+	if options.Padding < 0 || options.Padding*2 >= layout.Width || options.Padding*2 >= layout.Height {
+		return invalidArgument("GenerateDrunkWalk", "padding of %d doesn't leave any room in a %dx%d layout", options.Padding, layout.Width, layout.Height)
+	}
+
+	if options.MaxSteps < 0 {
+		return invalidArgument("GenerateDrunkWalk", "max steps can't be negative, got %d", options.MaxSteps)
+	}
+
+	for _, chance := range []float32{options.Momentum, options.SpawnChance, options.DeathChance} {
+		if !(chance >= 0 && chance <= 1) {
+			return invalidArgument("GenerateDrunkWalk", "momentum, spawn chance, and death chance must be between 0 and 1, got %v", chance)
+		}
+	}
+
+	if !(options.BiasX >= -1 && options.BiasX <= 1 && options.BiasY >= -1 && options.BiasY <= 1) {
+		return invalidArgument("GenerateDrunkWalk", "bias must be between -1 and 1, got %v, %v", options.BiasX, options.BiasY)
+	}
+
+	// Walkers can only carve inside the padding, so they'd never finish if they had to fill more than that.
+	inside := float32((layout.Width - options.Padding*2) * (layout.Height - options.Padding*2))
+
+	if options.PercentageFilled*float32(layout.Area()) > inside {
+		return fmt.Errorf("dngn: GenerateDrunkWalk: can't fill %v of the layout: %w", options.PercentageFilled, ErrCannotConverge)
+	}
+
+	return nil
+
+}
+
+// drunkWalker is a single walker used in Layout.GenerateDrunkWalkWithOptions().
+type drunkWalker struct {
+	Position
+	direction int // The last direction moved in, or -1 if the walker hasn't moved yet.
+	steps     int
+}
+
+// GenerateDrunkWalkWithOptions generates a map using drunk walking (see GenerateDrunkWalk()), configured through the options provided.
+// Walkers each take a step in turn, carving out the cells under them, until at least PercentageFilled of the Layout is filled.
+// On hex grids, walkers move from hex to hex in any of the six directions, rather than in the four cardinal directions.
+// It returns a RoomGraph containing a Room for each separate cave, or an error if the options are invalid (see DrunkWalkOptions).
+// GenerateDrunkWalkWithOptions is the same as GenerateDrunkWalkWithOptionsContext() with a background context.
+func (layout *Layout) GenerateDrunkWalkWithOptions(options DrunkWalkOptions) (*RoomGraph, error) {
+	return layout.GenerateDrunkWalkWithOptionsContext(context.Background(), options)
+}
+
+// GenerateDrunkWalkWithOptionsContext works like GenerateDrunkWalkWithOptions(), but stops and returns an error if the context is
+// cancelled or the Layout's StepBudget runs out before generation finishes.
+func (layout *Layout) GenerateDrunkWalkWithOptionsContext(ctx context.Context, options DrunkWalkOptions) (*RoomGraph, error) {
+
+	if err := layout.validateSize("GenerateDrunkWalk"); err != nil {
+		return nil, err
+	}
+
+	if err := options.validate(layout); err != nil {
+		return nil, err
+	}
+
+	layout.Select().Fill(options.WallValue)
+
+	gen := layout.generation(ctx, "drunk")
+
+	minX, minY := options.Padding, options.Padding
+	maxX, maxY := layout.Width-1-options.Padding, layout.Height-1-options.Padding
+
+	randomPosition := func() Position {
+		x := minX + layout.RNG.Intn(maxX-minX+1)
+		y := minY + layout.RNG.Intn(maxY-minY+1)
+		return Position{x, y}
+	}
+
+	floorCells := []Position{}
+
+	restart := func(walker *drunkWalker) {
+		if options.RestartFromFloor && len(floorCells) > 0 {
+			walker.Position = floorCells[layout.RNG.Intn(len(floorCells))]
+		} else {
+			walker.Position = randomPosition()
+		}
+		walker.direction = -1
+		walker.steps = 0
+	}
+
+	walkers := []*drunkWalker{}
+
+	for i := 0; i < options.Walkers; i++ {
+		walker := &drunkWalker{direction: -1}
+		if i < len(options.StartPositions) {
+			walker.Position = Position{clampInt(options.StartPositions[i].X, minX, maxX), clampInt(options.StartPositions[i].Y, minY, maxY)}
+		} else {
+			walker.Position = randomPosition()
+		}
+		walkers = append(walkers, walker)
+	}
+
+	maxWalkers := options.MaxWalkers
+	if maxWalkers < options.Walkers {
+		maxWalkers = options.Walkers
+	}
+
+	// The weight of each direction (right, left, down, up), according to the bias.
+	weights := []float32{1 + options.BiasX, 1 - options.BiasX, 1 + options.BiasY, 1 - options.BiasY}
+	biased := options.BiasX != 0 || options.BiasY != 0
+	directions := []Position{{1, 0}, {-1, 0}, {0, 1}, {0, -1}}
+
+	// On hex grids, walkers move from hex to hex instead, and each of the six directions is weighted by how far it goes right and
+	// down on screen. The weights are scaled to add up to the number of directions, like the square grid's weights do.
+	if layout.Grid.IsHex() {
+
+		weights = make([]float32, len(hexDirections))
+		total := float32(0)
+
+		for dir, offset := range hexDirections {
+			x, y := layout.screenDirection(offset)
+			weights[dir] = 1 + options.BiasX*float32(x) + options.BiasY*float32(y)
+			if weights[dir] < 0 {
+				weights[dir] = 0
+			}
+			total += weights[dir]
+		}
+
+		for dir := range weights {
+			weights[dir] *= float32(len(weights)) / total
+		}
+
+	}
+
+	pickDirection := func() int {
+
+		if !biased {
+			return layout.RNG.Intn(len(weights))
+		}
+
+		roll := layout.RNG.Float32() * float32(len(weights))
+		for dir, weight := range weights {
+			if roll < weight {
+				return dir
+			}
+			roll -= weight
+		}
+		return len(weights) - 1
+
+	}
+
+	fillCount := float32(0)
+	totalArea := float32(layout.Area())
+	brushOffset := (options.BrushSize - 1) / 2
+
+	for {
+
+		for i := 0; i < len(walkers); i++ {
+
+			walker := walkers[i]
+
+			step := GenerationStep{Kind: StepWalk, X: walker.X - brushOffset, Y: walker.Y - brushOffset, W: options.BrushSize, H: options.BrushSize}
+
+			for y := step.Y; y < step.Y+step.H; y++ {
+				for x := step.X; x < step.X+step.W; x++ {
+					if x >= minX && y >= minY && x <= maxX && y <= maxY && layout.Get(x, y) != options.FloorValue {
+						layout.Set(x, y, options.FloorValue)
+						fillCount++
+						step.Kind = StepCarve
+						if options.RestartFromFloor {
+							floorCells = append(floorCells, Position{x, y})
+						}
+					}
+				}
+			}
+
+			if err := gen.step(step); err != nil {
+				return nil, err
+			}
+
+			if options.SpawnChance > 0 && len(walkers) < maxWalkers && layout.RNG.Float32() < options.SpawnChance {
+				walkers = append(walkers, &drunkWalker{Position: walker.Position, direction: -1})
+			}
+
+			if options.DeathChance > 0 && layout.RNG.Float32() < options.DeathChance {
+				if len(walkers) > 1 {
+					walkers = append(walkers[:i], walkers[i+1:]...)
+					i--
+					continue
+				}
+				restart(walker)
+			}
+
+			walker.steps++
+
+			if options.MaxSteps > 0 && walker.steps >= options.MaxSteps {
+				restart(walker)
+			}
+
+			dir := walker.direction
+			if dir < 0 || options.Momentum == 0 || layout.RNG.Float32() >= options.Momentum {
+				dir = pickDirection()
+			}
+
+			var next Position
+			if layout.Grid.IsHex() {
+				next = layout.Position(layout.Hex(walker.X, walker.Y).Add(hexDirections[dir]))
+			} else {
+				next = Position{walker.X + directions[dir].X, walker.Y + directions[dir].Y}
+			}
+
+			moved := Position{clampInt(next.X, minX, maxX), clampInt(next.Y, minY, maxY)}
+
+			// Walkers that run into the edge lose their momentum, rather than sliding along it.
+			walker.direction = dir
+			if moved == walker.Position {
+				walker.direction = -1
+			}
+
+			walker.Position = moved
+
+			if fillCount/totalArea >= options.PercentageFilled {
+				return layout.regionGraph(options.FloorValue), nil
+			}
+
+		}
+
+	}
+
+}
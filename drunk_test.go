@@ -0,0 +1,55 @@
+package dngn
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// TestDrunkWalkPercentageFilled checks that percentages the walk could never reach, and NaN chances and biases, are rejected
+// instead of walking forever.
+func TestDrunkWalkPercentageFilled(t *testing.T) {
+
+	for _, percentage := range []float32{float32(math.NaN()), -0.5, 1.5} {
+
+		layout := NewLayout(30, 20)
+		layout.RNG = rand.New(rand.NewSource(1))
+
+		options := NewDefaultDrunkWalkOptions()
+		options.PercentageFilled = percentage
+
+		if _, err := layout.GenerateDrunkWalkWithOptions(options); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("percentage filled of %v: expected ErrInvalidArgument, got %v", percentage, err)
+		}
+
+		if _, err := layout.GenerateDrunkWalk(' ', 'x', percentage); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("legacy percentage filled of %v: expected ErrInvalidArgument, got %v", percentage, err)
+		}
+
+	}
+
+	// The other chances and biases can't be NaN either; a NaN bias, for example, would send every walker straight up forever.
+	nan := float32(math.NaN())
+
+	for name, set := range map[string]func(options *DrunkWalkOptions){
+		"momentum":     func(options *DrunkWalkOptions) { options.Momentum = nan },
+		"spawn chance": func(options *DrunkWalkOptions) { options.SpawnChance = nan },
+		"death chance": func(options *DrunkWalkOptions) { options.DeathChance = nan },
+		"bias x":       func(options *DrunkWalkOptions) { options.BiasX = nan },
+		"bias y":       func(options *DrunkWalkOptions) { options.BiasY = nan },
+	} {
+
+		layout := NewLayout(30, 20)
+		layout.RNG = rand.New(rand.NewSource(1))
+
+		options := NewDefaultDrunkWalkOptions()
+		set(&options)
+
+		if _, err := layout.GenerateDrunkWalkWithOptions(options); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("%s of NaN: expected ErrInvalidArgument, got %v", name, err)
+		}
+
+	}
+
+}
@@ -62,36 +62,30 @@ func (game *Game) GenerateMap() {
 		bspOptions.SplitCount = 60
 		bspOptions.MinimumRoomSize = 3
 
-		bspRooms := game.Map.GenerateBSP(bspOptions)
+		bsp, err := game.Map.GenerateBSP(bspOptions)
+		if err != nil {
+			panic(err)
+		}
+
+		bspRooms := bsp.Rooms
 
 		// Just generating BSP rooms is pretty good, but we can modify it a bit afterwards.
 		// Below, we select a room to specify as the "start", and then effectively destroy
 		// any rooms that are too far away (at least 5 hops away).
 
-		start := bspRooms[0]
-
-		for _, subroom := range bspRooms {
-
-			subroomCenter := subroom.Center()
-			center := game.Map.Center()
-
-			margin := 10
-
-			if subroomCenter.X > center.X-margin && subroomCenter.X < center.X+margin && subroomCenter.Y > center.Y-margin && subroomCenter.Y < center.Y+margin {
-				start = subroom
-				break
-			}
-
-		}
+		// AssignRoles() can tag rooms with gameplay roles (start, exit, boss, and so on); the default start room is the one nearest the center.
+		start := dngn.AssignRoles(bspRooms, dngn.RoleRules{Start: dngn.NewDefaultRoleRules().Start})[dngn.RoleStart][0]
 
 		for _, room := range bspRooms {
 
 			hops := room.CountHopsTo(start)
 
 			if hops < 0 || hops > 4 {
-				// We're filtering out a little bit more on the width and height because the walls and doorways in GenerateBSP() are always on the top and left sides of each room.
-				// By adding the right and bottom as well, we can nuke any doors that led into rooms we're deleting.
-				mapSelection.FilterByArea(room.X, room.Y, room.W+1, room.H+1).Fill('x')
+				// Fill in the room, as well as any doors that led into it (the doors on the room's right and bottom sides are in its neighbors' walls).
+				mapSelection.FilterByArea(room.X, room.Y, room.W, room.H).Fill('x')
+				for _, door := range room.Doors {
+					game.Map.Set(door.X, door.Y, 'x')
+				}
 				room.Disconnect()
 			}
 
@@ -102,10 +96,14 @@ func (game *Game) GenerateMap() {
 
 	} else if game.GenerationMode == 1 {
 
-		game.Map.GenerateDrunkWalk(' ', 'x', 0.5)
+		if _, err := game.Map.GenerateDrunkWalk(' ', 'x', 0.5); err != nil {
+			panic(err)
+		}
 
 	} else {
-		game.Map.GenerateRandomRooms(' ', 'x', 6, 3, 3, 5, 5, true)
+		if _, err := game.Map.GenerateRandomRooms(' ', 'x', 6, 3, 3, 5, 5, true); err != nil {
+			panic(err)
+		}
 
 		// This selects the ground tiles that are between walls to place doors randomly. This isn't really good, but it at least
 		// gets the idea across.
@@ -0,0 +1,311 @@
+package dngn
+
+// This file contains functions to analyze the graph formed by rooms and their Connected lists. Functions that take a list of rooms
+// only consider connections between the rooms in that list, while methods on a single Room follow all of its connections.
+
+// HopsFrom returns the number of hops it takes to get from this room to every room that can be reached from it (including itself,
+// at 0 hops), going through connected neighbors. Like CountHopsTo(), rooms that aren't Traversable can be reached, but not passed
+// through.
+func (bsp *Room) HopsFrom() map[*Room]int {
+	hops := map[*Room]int{}
+	bsp.breadthFirst(nil, func(room *Room, roomHops int) {
+		hops[room] = roomHops
+	})
+	return hops
+}
+
+// breadthFirst calls the function provided for each room that can be reached from this room, in order of the number of hops it
+// takes to reach them (starting with this room itself). If inGraph isn't nil, only the rooms in it are followed.
+func (bsp *Room) breadthFirst(inGraph map[*Room]bool, forEach func(room *Room, hops int)) {
+
+	hops := map[*Room]int{bsp: 0}
+	toCheck := []*Room{bsp}
+
+	for len(toCheck) > 0 {
+
+		next := toCheck[0]
+		toCheck = toCheck[1:]
+
+		forEach(next, hops[next])
+
+		if !next.Traversable && next != bsp {
+			continue
+		}
+
+		for _, connected := range next.Connected {
+			if inGraph != nil && !inGraph[connected] {
+				continue
+			}
+			if _, exists := hops[connected]; !exists {
+				hops[connected] = hops[next] + 1
+				toCheck = append(toCheck, connected)
+			}
+		}
+
+	}
+
+}
+
+// PathTo returns the shortest path of rooms going from this room to the room provided, through connected neighbors. The path
+// starts with this room and ends with the destination room. If there's no traversable path between the two rooms, PathTo returns nil.
+func (bsp *Room) PathTo(room *Room) []*Room {
+
+	from := map[*Room]*Room{bsp: nil}
+	toCheck := []*Room{bsp}
+
+	for len(toCheck) > 0 {
+
+		next := toCheck[0]
+		toCheck = toCheck[1:]
+
+		if next == room {
+
+			path := []*Room{}
+
+			for r := room; r != nil; r = from[r] {
+				path = append([]*Room{r}, path...)
+			}
+
+			return path
+
+		}
+
+		if !next.Traversable && next != bsp {
+			continue
+		}
+
+		for _, connected := range next.Connected {
+			if _, exists := from[connected]; !exists {
+				from[connected] = next
+				toCheck = append(toCheck, connected)
+			}
+		}
+
+	}
+
+	return nil
+
+}
+
+// Eccentricity returns the number of hops from this room to the room farthest from it (out of the rooms that can be reached from it).
+func (bsp *Room) Eccentricity() int {
+	return bsp.eccentricity(nil)
+}
+
+// eccentricity returns the number of hops from this room to the room farthest from it, only going through the rooms in inGraph
+// (or every room, if it's nil).
+func (bsp *Room) eccentricity(inGraph map[*Room]bool) int {
+
+	most := 0
+
+	bsp.breadthFirst(inGraph, func(room *Room, hops int) {
+		if hops > most {
+			most = hops
+		}
+	})
+
+	return most
+
+}
+
+// Farthest returns the room that takes the most hops to reach from this room, along with the number of hops. If there are several,
+// the one found first is returned. If no other rooms can be reached, the room itself is returned, with 0 hops.
+func (bsp *Room) Farthest() (*Room, int) {
+
+	farthest := bsp
+	most := 0
+
+	bsp.breadthFirst(nil, func(room *Room, hops int) {
+		if hops > most {
+			farthest = room
+			most = hops
+		}
+	})
+
+	return farthest, most
+
+}
+
+// RoomDiameter returns the largest number of hops it takes to get from any room in the list to any other room reachable from it,
+// going only through the rooms in the list.
+func RoomDiameter(rooms []*Room) int {
+
+	inGraph := roomSet(rooms)
+	diameter := 0
+
+	for _, room := range rooms {
+		if e := room.eccentricity(inGraph); e > diameter {
+			diameter = e
+		}
+	}
+
+	return diameter
+
+}
+
+// DeadEndRooms returns the rooms in the list that are only connected to a single other room in the list.
+func DeadEndRooms(rooms []*Room) []*Room {
+
+	inGraph := roomSet(rooms)
+	deadEnds := []*Room{}
+
+	for _, room := range rooms {
+
+		neighbors := 0
+
+		for _, connected := range room.Connected {
+			if inGraph[connected] {
+				neighbors++
+			}
+		}
+
+		if neighbors == 1 {
+			deadEnds = append(deadEnds, room)
+		}
+
+	}
+
+	return deadEnds
+
+}
+
+// ArticulationRooms returns the rooms in the list that are chokepoints; that is, rooms that, if removed, would cut off some of
+// the other rooms from each other. This runs in linear time (in the number of rooms and connections).
+func ArticulationRooms(rooms []*Room) []*Room {
+
+	search := newRoomGraphSearch(rooms)
+
+	articulation := []*Room{}
+
+	for _, room := range rooms {
+		if search.articulation[room] {
+			articulation = append(articulation, room)
+		}
+	}
+
+	return articulation
+
+}
+
+// BridgeConnections returns the connections between rooms in the list that are the only way to get from one side of the connection
+// to the other; removing any one of them would split the rooms in two. Each connection is returned as a pair of rooms.
+func BridgeConnections(rooms []*Room) [][2]*Room {
+	return newRoomGraphSearch(rooms).bridges
+}
+
+// HasCycles returns if there are any loops in the connections between the rooms in the list (i.e. if there's more than one way to
+// get from one room to another).
+func HasCycles(rooms []*Room) bool {
+	return len(CycleRooms(rooms)) > 0
+}
+
+// CycleRooms returns the rooms in the list that are part of at least one loop.
+func CycleRooms(rooms []*Room) []*Room {
+
+	search := newRoomGraphSearch(rooms)
+
+	isBridge := map[[2]*Room]bool{}
+
+	for _, bridge := range search.bridges {
+		isBridge[bridge] = true
+		isBridge[[2]*Room{bridge[1], bridge[0]}] = true
+	}
+
+	// A room is on a loop if any of its connections aren't bridges.
+	cycleRooms := []*Room{}
+
+	for _, room := range rooms {
+		for _, connected := range room.Connected {
+			if search.inGraph[connected] && connected != room && !isBridge[[2]*Room{room, connected}] {
+				cycleRooms = append(cycleRooms, room)
+				break
+			}
+		}
+	}
+
+	return cycleRooms
+
+}
+
+// roomGraphSearch finds the articulation points (chokepoint rooms) and bridges (chokepoint connections) in a graph of rooms using
+// Tarjan's depth-first search.
+type roomGraphSearch struct {
+	inGraph      map[*Room]bool
+	index        map[*Room]int
+	low          map[*Room]int
+	articulation map[*Room]bool
+	bridges      [][2]*Room
+}
+
+func newRoomGraphSearch(rooms []*Room) *roomGraphSearch {
+
+	search := &roomGraphSearch{
+		inGraph:      roomSet(rooms),
+		index:        map[*Room]int{},
+		low:          map[*Room]int{},
+		articulation: map[*Room]bool{},
+		bridges:      [][2]*Room{},
+	}
+
+	for _, room := range rooms {
+		if _, visited := search.index[room]; !visited {
+			search.visit(room, nil)
+		}
+	}
+
+	return search
+
+}
+
+func (search *roomGraphSearch) visit(room, parent *Room) {
+
+	search.index[room] = len(search.index)
+	search.low[room] = search.index[room]
+
+	children := 0
+
+	for _, next := range room.Connected {
+
+		if !search.inGraph[next] || next == parent || next == room {
+			continue
+		}
+
+		if _, visited := search.index[next]; visited {
+			if search.index[next] < search.low[room] {
+				search.low[room] = search.index[next]
+			}
+			continue
+		}
+
+		children++
+
+		search.visit(next, room)
+
+		if search.low[next] < search.low[room] {
+			search.low[room] = search.low[next]
+		}
+
+		if parent != nil && search.low[next] >= search.index[room] {
+			search.articulation[room] = true
+		}
+
+		if search.low[next] > search.index[room] {
+			search.bridges = append(search.bridges, [2]*Room{room, next})
+		}
+
+	}
+
+	if parent == nil && children > 1 {
+		search.articulation[room] = true
+	}
+
+}
+
+// roomSet returns a set containing the rooms provided.
+func roomSet(rooms []*Room) map[*Room]bool {
+	set := map[*Room]bool{}
+	for _, room := range rooms {
+		set[room] = true
+	}
+	return set
+}
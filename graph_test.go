@@ -0,0 +1,98 @@
+package dngn
+
+import "testing"
+
+// testRoomGraph returns rooms connected in a line (a - b - c - d) that ends in a loop (d - e - f - d).
+func testRoomGraph() (a, b, c, d, e, f *Room) {
+
+	a, b, c, d, e, f = NewRoom(0, 0, 1, 1), NewRoom(1, 0, 1, 1), NewRoom(2, 0, 1, 1), NewRoom(3, 0, 1, 1), NewRoom(4, 0, 1, 1), NewRoom(4, 1, 1, 1)
+
+	a.connect(b)
+	b.connect(c)
+	c.connect(d)
+	d.connect(e)
+	e.connect(f)
+	f.connect(d)
+
+	return
+
+}
+
+// sameRooms returns if the two lists contain the same rooms, in any order.
+func sameRooms(a, b []*Room) bool {
+
+	if len(a) != len(b) {
+		return false
+	}
+
+	set := roomSet(a)
+
+	for _, room := range b {
+		if !set[room] {
+			return false
+		}
+	}
+
+	return true
+
+}
+
+// TestRoomGraph checks the graph queries against a small graph with a known shape.
+func TestRoomGraph(t *testing.T) {
+
+	a, b, c, d, e, f := testRoomGraph()
+	rooms := []*Room{a, b, c, d, e, f}
+
+	if diameter := RoomDiameter(rooms); diameter != 4 {
+		t.Errorf("expected a diameter of 4, got %d", diameter)
+	}
+
+	if articulation := ArticulationRooms(rooms); !sameRooms(articulation, []*Room{b, c, d}) {
+		t.Errorf("expected b, c, and d to be chokepoints, got %d rooms", len(articulation))
+	}
+
+	if bridges := BridgeConnections(rooms); len(bridges) != 3 {
+		t.Errorf("expected 3 bridges, got %d", len(bridges))
+	}
+
+	if !HasCycles(rooms) {
+		t.Errorf("expected a loop")
+	}
+
+	if cycle := CycleRooms(rooms); !sameRooms(cycle, []*Room{d, e, f}) {
+		t.Errorf("expected d, e, and f to be on a loop, got %d rooms", len(cycle))
+	}
+
+	if deadEnds := DeadEndRooms(rooms); !sameRooms(deadEnds, []*Room{a}) {
+		t.Errorf("expected a to be the only dead end, got %d rooms", len(deadEnds))
+	}
+
+	if path := a.PathTo(f); len(path) != 5 || path[0] != a || path[3] != d || path[4] != f {
+		t.Errorf("expected a path of 5 rooms from a to f, got %d", len(path))
+	}
+
+}
+
+// TestRoomSubGraph checks that the graph queries only follow connections between the rooms they're given.
+func TestRoomSubGraph(t *testing.T) {
+
+	a, b, c, _, _, _ := testRoomGraph()
+	rooms := []*Room{a, b, c}
+
+	if diameter := RoomDiameter(rooms); diameter != 2 {
+		t.Errorf("expected a diameter of 2, got %d", diameter)
+	}
+
+	if articulation := ArticulationRooms(rooms); !sameRooms(articulation, []*Room{b}) {
+		t.Errorf("expected b to be the only chokepoint, got %d rooms", len(articulation))
+	}
+
+	if HasCycles(rooms) {
+		t.Errorf("expected no loops")
+	}
+
+	if deadEnds := DeadEndRooms(rooms); !sameRooms(deadEnds, []*Room{a, c}) {
+		t.Errorf("expected a and c to be dead ends, got %d rooms", len(deadEnds))
+	}
+
+}
@@ -0,0 +1,181 @@
+package dngn
+
+import (
+	"fmt"
+	"math"
+)
+
+// GridType is the shape of the cells in a Layout, which decides which cells neighbor each other (see Layout.Neighbors()).
+// Hex grids are stored in the Layout's Data using offset coordinates: each row (for pointy-topped hexes) or column (for flat-topped
+// hexes) is shoved over by half of a hex from the one before it, so that the hexes still fit into a rectangular array. Layout.Hex()
+// and Layout.Position() convert between these and axial coordinates, which are easier to do math with.
+type GridType int
+
+const (
+	GridSquare        GridType = iota // Square cells, with 4 neighbors (or 8, counting diagonals).
+	GridHexPointyOdd                  // Pointy-topped hexes in rows, with odd rows shoved right ("odd-r").
+	GridHexPointyEven                 // Pointy-topped hexes in rows, with even rows shoved right ("even-r").
+	GridHexFlatOdd                    // Flat-topped hexes in columns, with odd columns shoved down ("odd-q").
+	GridHexFlatEven                   // Flat-topped hexes in columns, with even columns shoved down ("even-q").
+)
+
+// String returns the name of the GridType: "square", "hex-pointy-odd", "hex-pointy-even", "hex-flat-odd", or "hex-flat-even".
+func (grid GridType) String() string {
+	switch grid {
+	case GridSquare:
+		return "square"
+	case GridHexPointyOdd:
+		return "hex-pointy-odd"
+	case GridHexPointyEven:
+		return "hex-pointy-even"
+	case GridHexFlatOdd:
+		return "hex-flat-odd"
+	case GridHexFlatEven:
+		return "hex-flat-even"
+	}
+	return fmt.Sprintf("GridType(%d)", int(grid))
+}
+
+// ParseGridType returns the GridType with the name provided (see GridType.String()), or an error wrapping ErrInvalidArgument if there
+// isn't one.
+func ParseGridType(name string) (GridType, error) {
+	for grid := GridSquare; grid <= GridHexFlatEven; grid++ {
+		if grid.String() == name {
+			return grid, nil
+		}
+	}
+	return GridSquare, invalidArgument("ParseGridType", "unknown grid %q; expected square, hex-pointy-odd, hex-pointy-even, hex-flat-odd, or hex-flat-even", name)
+}
+
+// IsHex returns if the GridType is one of the hex grids.
+func (grid GridType) IsHex() bool {
+	return grid >= GridHexPointyOdd && grid <= GridHexFlatEven
+}
+
+// Hex is a position on a hex grid in axial coordinates. Q goes along the grid's rows (for pointy-topped hexes) or columns (for
+// flat-topped hexes), and R goes diagonally across them, so that moving in any of the six directions changes Q and R by at most 1.
+type Hex struct {
+	Q, R int
+}
+
+// hexDirections are the offsets to each of a Hex's six neighbors, going around counter-clockwise (for pointy-topped hexes, starting
+// from the right).
+var hexDirections = []Hex{{1, 0}, {1, -1}, {0, -1}, {-1, 0}, {-1, 1}, {0, 1}}
+
+// Add returns the sum of the two Hexes.
+func (hex Hex) Add(other Hex) Hex {
+	return Hex{hex.Q + other.Q, hex.R + other.R}
+}
+
+// Neighbors returns the six Hexes next to the Hex.
+func (hex Hex) Neighbors() []Hex {
+	neighbors := make([]Hex, 0, len(hexDirections))
+	for _, dir := range hexDirections {
+		neighbors = append(neighbors, hex.Add(dir))
+	}
+	return neighbors
+}
+
+// Distance returns the number of steps it takes to get from one Hex to the other.
+func (hex Hex) Distance(other Hex) int {
+	dq, dr := hex.Q-other.Q, hex.R-other.R
+	return (absInt(dq) + absInt(dr) + absInt(dq+dr)) / 2
+}
+
+// Hex returns the axial coordinates of the cell at the position provided in the Layout's Data, according to the Layout's Grid. For
+// square grids, the position is returned as it is.
+func (layout *Layout) Hex(x, y int) Hex {
+
+	switch layout.Grid {
+	case GridHexPointyOdd:
+		return Hex{x - (y-y&1)/2, y}
+	case GridHexPointyEven:
+		return Hex{x - (y+y&1)/2, y}
+	case GridHexFlatOdd:
+		return Hex{x, y - (x-x&1)/2}
+	case GridHexFlatEven:
+		return Hex{x, y - (x+x&1)/2}
+	}
+
+	return Hex{x, y}
+
+}
+
+// Position returns the position in the Layout's Data of the cell at the axial coordinates provided; it's the opposite of Layout.Hex().
+func (layout *Layout) Position(hex Hex) Position {
+
+	switch layout.Grid {
+	case GridHexPointyOdd:
+		return Position{hex.Q + (hex.R-hex.R&1)/2, hex.R}
+	case GridHexPointyEven:
+		return Position{hex.Q + (hex.R+hex.R&1)/2, hex.R}
+	case GridHexFlatOdd:
+		return Position{hex.Q, hex.R + (hex.Q-hex.Q&1)/2}
+	case GridHexFlatEven:
+		return Position{hex.Q, hex.R + (hex.Q+hex.Q&1)/2}
+	}
+
+	return Position{hex.Q, hex.R}
+
+}
+
+// Neighbors returns the positions of the cells next to the cell at the position provided, according to the Layout's Grid. On square
+// grids, these are the 4 cells in the cardinal directions, followed by the 4 diagonal ones if diagonal is true; on hex grids, these
+// are the 6 cells around the hex, and diagonal is ignored. The positions can be outside of the Layout.
+func (layout *Layout) Neighbors(x, y int, diagonal bool) []Position {
+
+	if layout.Grid.IsHex() {
+		neighbors := make([]Position, 0, len(hexDirections))
+		hex := layout.Hex(x, y)
+		for _, dir := range hexDirections {
+			neighbors = append(neighbors, layout.Position(hex.Add(dir)))
+		}
+		return neighbors
+	}
+
+	neighbors := []Position{{x - 1, y}, {x + 1, y}, {x, y - 1}, {x, y + 1}}
+
+	if diagonal {
+		neighbors = append(neighbors, Position{x - 1, y - 1}, Position{x + 1, y - 1}, Position{x - 1, y + 1}, Position{x + 1, y + 1})
+	}
+
+	return neighbors
+
+}
+
+// GridDistance returns the number of steps it takes to get from one position to the other, moving from neighbor to neighbor (see
+// Layout.Neighbors()) without anything in the way. On square grids, this is the Manhattan distance, or the Chebyshev distance if
+// diagonal is true; on hex grids, it's the distance between the hexes, and diagonal is ignored.
+func (layout *Layout) GridDistance(a, b Position, diagonal bool) int {
+
+	if layout.Grid.IsHex() {
+		return layout.Hex(a.X, a.Y).Distance(layout.Hex(b.X, b.Y))
+	}
+
+	dx, dy := absInt(a.X-b.X), absInt(a.Y-b.Y)
+
+	if diagonal {
+		return maxInt(dx, dy)
+	}
+
+	return dx + dy
+
+}
+
+// screenDirection returns the direction on screen (with Y pointing down) of the Hex offset provided, as a unit vector, according to
+// the Layout's Grid.
+func (layout *Layout) screenDirection(dir Hex) (float64, float64) {
+
+	var x, y float64
+
+	if layout.Grid == GridHexFlatOdd || layout.Grid == GridHexFlatEven {
+		x, y = 1.5*float64(dir.Q), math.Sqrt(3)*(float64(dir.R)+float64(dir.Q)/2)
+	} else {
+		x, y = math.Sqrt(3)*(float64(dir.Q)+float64(dir.R)/2), 1.5*float64(dir.R)
+	}
+
+	length := math.Hypot(x, y)
+
+	return x / length, y / length
+
+}
@@ -0,0 +1,36 @@
+package dngn
+
+import "testing"
+
+// TestHexRoundTrip checks that converting between offset and axial coordinates gets back to where it started on every hex grid,
+// including at negative coordinates outside of the Layout.
+func TestHexRoundTrip(t *testing.T) {
+
+	for _, grid := range []GridType{GridHexPointyOdd, GridHexPointyEven, GridHexFlatOdd, GridHexFlatEven} {
+
+		layout := NewLayout(4, 4)
+		layout.Grid = grid
+
+		for y := -5; y <= 5; y++ {
+			for x := -5; x <= 5; x++ {
+
+				if position := layout.Position(layout.Hex(x, y)); position != (Position{x, y}) {
+					t.Errorf("%s: %v went to %v and back to %v", grid, Position{x, y}, layout.Hex(x, y), position)
+				}
+
+				if hex := layout.Hex(layout.Position(Hex{x, y}).X, layout.Position(Hex{x, y}).Y); hex != (Hex{x, y}) {
+					t.Errorf("%s: %v went to %v and back to %v", grid, Hex{x, y}, layout.Position(Hex{x, y}), hex)
+				}
+
+				for _, neighbor := range layout.Neighbors(x, y, false) {
+					if distance := layout.GridDistance(Position{x, y}, neighbor, false); distance != 1 {
+						t.Errorf("%s: neighbor %v of %v is %d steps away", grid, neighbor, Position{x, y}, distance)
+					}
+				}
+
+			}
+		}
+
+	}
+
+}
@@ -0,0 +1,313 @@
+package dngn
+
+import (
+	"fmt"
+)
+
+// Lock is a locked room placed through Layout.PlaceLocks(). Every door leading into Room is locked, and opened by the key found
+// in the Key room. Color is the lock's (and its key's) colour index, for drawing and telling keys apart; each Lock still has its
+// own key, even when several Locks share a colour.
+type Lock struct {
+	Color int
+	Room  *Room
+	Key   *Room
+	Doors []Door // The locked doors (Room's doors); this is empty for rooms that have no doors, like those from graph-only generators.
+}
+
+// LockOptions configures Layout.PlaceLocks().
+type LockOptions struct {
+	Locks  int  // The number of locks to place.
+	Colors int  // The number of different key colours; lock i is given colour i % Colors.
+	Nested bool // If keys should be placed behind the previous lock, so that the locks have to be opened in order.
+
+	// The room the player starts in. If nil, the first room tagged RoleStart is used (see AssignRoles()), or the first room if
+	// none are tagged.
+	Start *Room
+
+	// The room the player needs to reach. If set, the last lock will keep it locked away. If nil, the first room tagged
+	// RoleExit is used, if any.
+	Goal *Room
+
+	// If set, locked doors are drawn in the Layout using the rune for their lock's colour (LockValues[Color]); there should be at
+	// least Colors runes.
+	LockValues []rune
+}
+
+// NewDefaultLockOptions returns LockOptions for three nested locks, each with a different colour.
+func NewDefaultLockOptions() LockOptions {
+	return LockOptions{
+		Locks:  3,
+		Colors: 3,
+		Nested: true,
+	}
+}
+
+// LockPlan is the result of placing locks and keys through Layout.PlaceLocks().
+type LockPlan struct {
+	Rooms []*Room
+	Start *Room
+	Goal  *Room // The goal room; this can be nil.
+	Locks []*Lock
+}
+
+// PlaceLocks places the number of locks and keys specified in the options provided on the rooms given, which can come from any
+// generator, as long as their Connected lists describe how they're connected. Locks are placed on whole rooms (every door into
+// a locked room is locked), and each key is placed where it can be reached, so the locks can always be opened; every placement
+// is checked by simulating a playthrough (see LockPlan.Solve()). If not all of the locks can be placed (for example, because there
+// aren't enough rooms), an error wrapping ErrCannotConverge is returned, along with the plan containing the locks that were placed.
+func (layout *Layout) PlaceLocks(rooms []*Room, opts LockOptions) (*LockPlan, error) {
+
+	if len(rooms) == 0 {
+		return nil, invalidArgument("PlaceLocks", "no rooms given")
+	}
+
+	if opts.Locks < 0 {
+		return nil, invalidArgument("PlaceLocks", "lock count must be non-negative, got %d", opts.Locks)
+	}
+
+	if opts.Colors < 1 {
+		return nil, invalidArgument("PlaceLocks", "color count must be at least 1, got %d", opts.Colors)
+	}
+
+	if len(opts.LockValues) > 0 && len(opts.LockValues) < opts.Colors {
+		return nil, invalidArgument("PlaceLocks", "%d lock values given for %d colors", len(opts.LockValues), opts.Colors)
+	}
+
+	plan := &LockPlan{
+		Rooms: rooms,
+		Start: opts.Start,
+		Goal:  opts.Goal,
+		Locks: []*Lock{},
+	}
+
+	for _, room := range rooms {
+		if plan.Start == nil && room.HasTag(RoleStart) {
+			plan.Start = room
+		}
+		if plan.Goal == nil && room.HasTag(RoleExit) {
+			plan.Goal = room
+		}
+	}
+
+	if plan.Start == nil {
+		plan.Start = rooms[0]
+	}
+
+	if plan.Goal == plan.Start {
+		return nil, invalidArgument("PlaceLocks", "the goal can't be the start room")
+	}
+
+	if _, solvable := plan.Solve(); !solvable {
+		return nil, invalidArgument("PlaceLocks", "the goal can't be reached from the start room")
+	}
+
+	for i := 0; i < opts.Locks; i++ {
+
+		lock := layout.placeLock(plan, opts, i == opts.Locks-1)
+
+		if lock == nil {
+			return plan, fmt.Errorf("dngn: PlaceLocks: could only place %d of %d locks: %w", i, opts.Locks, ErrCannotConverge)
+		}
+
+		lock.Color = i % opts.Colors
+		lock.Doors = append([]Door{}, lock.Room.Doors...)
+
+		if len(opts.LockValues) > 0 {
+			for _, door := range lock.Doors {
+				layout.Set(door.X, door.Y, opts.LockValues[lock.Color])
+			}
+		}
+
+	}
+
+	return plan, nil
+
+}
+
+// placeLock adds a lock and its key to the plan and returns it, or returns nil if there's nowhere to put one.
+func (layout *Layout) placeLock(plan *LockPlan, opts LockOptions, last bool) *Lock {
+
+	// The rooms that can be reached once every key so far has been found.
+	open := plan.reachable(len(plan.Locks))
+
+	// With nested locks, keys go in the rooms that were opened by the previous lock.
+	keyRooms := open
+	if opts.Nested && len(plan.Locks) > 0 {
+		before := plan.reachable(len(plan.Locks) - 1)
+		keyRooms = map[*Room]bool{}
+		for room := range open {
+			if !before[room] {
+				keyRooms[room] = true
+			}
+		}
+	}
+
+	lockRooms := plan.sortedRooms(open)
+	layout.RNG.Shuffle(len(lockRooms), func(i, j int) { lockRooms[i], lockRooms[j] = lockRooms[j], lockRooms[i] })
+
+	keyOrder := plan.sortedRooms(keyRooms)
+	layout.RNG.Shuffle(len(keyOrder), func(i, j int) { keyOrder[i], keyOrder[j] = keyOrder[j], keyOrder[i] })
+
+	// Locks before the last one stay out of the way of the goal, so that the goal room itself can always be locked last.
+	var aroundGoal map[*Room]bool
+	if !last && plan.Goal != nil {
+		aroundGoal = plan.reachableWith(plan.openedLocks(len(plan.Locks)), plan.Goal)
+	}
+
+	for _, lockRoom := range lockRooms {
+
+		if lockRoom == plan.Start || plan.locked(lockRoom) || (aroundGoal != nil && !aroundGoal[lockRoom]) {
+			continue
+		}
+
+		lock := &Lock{Room: lockRoom}
+		plan.Locks = append(plan.Locks, lock)
+
+		// The rooms that can still be reached without the new lock's key (and, before the last lock, without going through the goal,
+		// so that no keys end up locked away with it).
+		reachable := plan.reachable(len(plan.Locks) - 1)
+		if aroundGoal != nil {
+			reachable = plan.reachableWith(plan.openedLocks(len(plan.Locks)-1), plan.Goal)
+		}
+
+		// The final lock has to keep the goal locked away.
+		if !last || plan.Goal == nil || !reachable[plan.Goal] {
+
+			for _, keyRoom := range keyOrder {
+
+				if !reachable[keyRoom] {
+					continue
+				}
+
+				lock.Key = keyRoom
+
+				if _, solvable := plan.Solve(); solvable {
+					return lock
+				}
+
+			}
+
+		}
+
+		plan.Locks = plan.Locks[:len(plan.Locks)-1]
+
+	}
+
+	return nil
+
+}
+
+// Solve simulates a playthrough of the plan, starting in the start room and repeatedly opening every lock whose key can be reached.
+// It returns the locks in the order they're opened, and if the plan is solvable; that is, if every lock can be opened and the goal
+// (if any) can be reached.
+func (plan *LockPlan) Solve() ([]*Lock, bool) {
+
+	opened := map[*Lock]bool{}
+	order := []*Lock{}
+
+	for {
+
+		reachable := plan.reachableWith(opened, nil)
+		progress := false
+
+		for _, lock := range plan.Locks {
+			if !opened[lock] && reachable[lock.Key] {
+				opened[lock] = true
+				order = append(order, lock)
+				progress = true
+			}
+		}
+
+		if !progress {
+			solvable := len(order) == len(plan.Locks) && (plan.Goal == nil || reachable[plan.Goal])
+			return order, solvable
+		}
+
+	}
+
+}
+
+// LockFor returns the lock on the room provided, or nil if the room isn't locked.
+func (plan *LockPlan) LockFor(room *Room) *Lock {
+	for _, lock := range plan.Locks {
+		if lock.Room == room {
+			return lock
+		}
+	}
+	return nil
+}
+
+// KeysIn returns the locks whose keys are in the room provided.
+func (plan *LockPlan) KeysIn(room *Room) []*Lock {
+	locks := []*Lock{}
+	for _, lock := range plan.Locks {
+		if lock.Key == room {
+			locks = append(locks, lock)
+		}
+	}
+	return locks
+}
+
+func (plan *LockPlan) locked(room *Room) bool {
+	return plan.LockFor(room) != nil
+}
+
+// reachable returns the rooms that can be reached from the start room with the keys of the first keyCount locks.
+func (plan *LockPlan) reachable(keyCount int) map[*Room]bool {
+	return plan.reachableWith(plan.openedLocks(keyCount), nil)
+}
+
+// openedLocks returns a set containing the first keyCount locks.
+func (plan *LockPlan) openedLocks(keyCount int) map[*Lock]bool {
+	opened := map[*Lock]bool{}
+	for _, lock := range plan.Locks[:keyCount] {
+		opened[lock] = true
+	}
+	return opened
+}
+
+// reachableWith returns the rooms that can be reached from the start room when only the locks given are open, without going
+// into the blocked room (if it isn't nil).
+func (plan *LockPlan) reachableWith(opened map[*Lock]bool, blocked *Room) map[*Room]bool {
+
+	inGraph := roomSet(plan.Rooms)
+	reachable := map[*Room]bool{plan.Start: true}
+	toCheck := []*Room{plan.Start}
+
+	for len(toCheck) > 0 {
+
+		next := toCheck[0]
+		toCheck = toCheck[1:]
+
+		for _, connected := range next.Connected {
+
+			if !inGraph[connected] || reachable[connected] || connected == blocked {
+				continue
+			}
+
+			if lock := plan.LockFor(connected); lock != nil && !opened[lock] {
+				continue
+			}
+
+			reachable[connected] = true
+			toCheck = append(toCheck, connected)
+
+		}
+
+	}
+
+	return reachable
+
+}
+
+// sortedRooms returns the rooms in the set provided, in the order they appear in the plan's room list (so that shuffling them
+// with the Layout's RNG is deterministic).
+func (plan *LockPlan) sortedRooms(set map[*Room]bool) []*Room {
+	rooms := []*Room{}
+	for _, room := range plan.Rooms {
+		if set[room] {
+			rooms = append(rooms, room)
+		}
+	}
+	return rooms
+}
@@ -0,0 +1,53 @@
+package dngn
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestPlaceLocks checks that placed locks can always be opened, in order when they're nested, and that the goal ends up behind
+// the last lock.
+func TestPlaceLocks(t *testing.T) {
+
+	for seed := int64(1); seed <= 10; seed++ {
+
+		layout := NewLayout(60, 40)
+		layout.RNG = rand.New(rand.NewSource(seed))
+
+		options := NewDefaultBSPOptions()
+		options.SplitCount = 20
+
+		result, err := layout.GenerateBSP(options)
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+
+		AssignRoles(result.Rooms, NewDefaultRoleRules())
+
+		plan, err := layout.PlaceLocks(result.Rooms, NewDefaultLockOptions())
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+
+		if len(plan.Locks) != 3 {
+			t.Errorf("seed %d: expected 3 locks, got %d", seed, len(plan.Locks))
+		}
+
+		order, solvable := plan.Solve()
+		if !solvable {
+			t.Errorf("seed %d: the plan can't be solved", seed)
+		}
+
+		for i, lock := range order {
+			if lock != plan.Locks[i] {
+				t.Errorf("seed %d: nested lock %d was opened out of order", seed, i)
+			}
+		}
+
+		if plan.Goal != nil && len(plan.Locks) > 0 && plan.reachable(len(plan.Locks) - 1)[plan.Goal] {
+			t.Errorf("seed %d: the goal can be reached without opening the last lock", seed)
+		}
+
+	}
+
+}
@@ -0,0 +1,521 @@
+package dngn
+
+import (
+	"context"
+	"math"
+	"math/rand"
+)
+
+// NoiseType is the kind of noise a Noise generates.
+type NoiseType int
+
+const (
+	NoiseValue       NoiseType = iota // Smoothly interpolated random values; blocky, but cheap.
+	NoisePerlin                       // Ken Perlin's improved gradient noise.
+	NoiseSimplex                      // Simplex noise; like Perlin noise, but with fewer grid-aligned artifacts.
+	NoiseOpenSimplex                  // OpenSimplex noise; similar to simplex noise, with smoother, less directional features.
+)
+
+// String returns the name of the NoiseType.
+func (noiseType NoiseType) String() string {
+	switch noiseType {
+	case NoiseValue:
+		return "value"
+	case NoisePerlin:
+		return "perlin"
+	case NoiseSimplex:
+		return "simplex"
+	case NoiseOpenSimplex:
+		return "opensimplex"
+	}
+	return "unknown"
+}
+
+// Noise is a deterministic, seeded 2D noise field, for generating continuous terrain (see Layout.GenerateFromNoise()). Noise
+// created with the same seed and settings always returns the same values. Several octaves of noise can be layered (fractal
+// Brownian motion) to add finer detail, and the field can be made ridged or domain-warped for mountain ranges and more natural,
+// swirling shapes.
+type Noise struct {
+	Type NoiseType
+
+	Frequency  float64 // How quickly the noise changes from cell to cell; smaller values make larger features.
+	Octaves    int     // How many layers of noise to add together; each octave adds finer detail.
+	Lacunarity float64 // How much the frequency is multiplied by for each octave.
+	Gain       float64 // How much the strength of each octave is multiplied by (also known as persistence).
+
+	// If true, the noise is folded into sharp ridges (ridged multifractal noise), which is useful for mountain ranges and
+	// rivers.
+	Ridged bool
+
+	// How far (in cells) the noise is warped by another noise field before being sampled; 0 disables domain warping.
+	WarpStrength float64
+	// The frequency of the noise used for warping; if 0, Frequency is used.
+	WarpFrequency float64
+
+	perm [512]int
+}
+
+// NewNoise returns a new Noise of the given type, seeded with the seed provided. It starts with a single octave at a frequency of
+// 0.05, so features are roughly 20 cells across.
+func NewNoise(noiseType NoiseType, seed int64) *Noise {
+
+	noise := &Noise{
+		Type:       noiseType,
+		Frequency:  0.05,
+		Octaves:    1,
+		Lacunarity: 2,
+		Gain:       0.5,
+	}
+
+	for i, value := range rand.New(rand.NewSource(seed)).Perm(256) {
+		noise.perm[i] = value
+		noise.perm[i+256] = value
+	}
+
+	return noise
+
+}
+
+// validate returns an error if the Noise's settings can't be used.
+func (noise *Noise) validate(function string) error {
+
+	if noise.Type < NoiseValue || noise.Type > NoiseOpenSimplex {
+		return invalidArgument(function, "unknown noise type %d", noise.Type)
+	}
+
+	// These are written so that NaN fails them too.
+	if !(noise.Frequency > 0) {
+		return invalidArgument(function, "noise frequency must be positive, got %v", noise.Frequency)
+	}
+
+	if noise.Octaves < 1 {
+		return invalidArgument(function, "noise octave count must be at least 1, got %d", noise.Octaves)
+	}
+
+	if !(noise.Lacunarity > 0 && noise.Gain > 0) {
+		return invalidArgument(function, "noise lacunarity and gain must be positive, got %v and %v", noise.Lacunarity, noise.Gain)
+	}
+
+	if !(noise.WarpStrength >= 0 && noise.WarpFrequency >= 0) {
+		return invalidArgument(function, "noise warp strength and frequency can't be negative, got %v and %v", noise.WarpStrength, noise.WarpFrequency)
+	}
+
+	return nil
+
+}
+
+// At returns the value of the noise field at the given position, roughly between -1 and 1.
+func (noise *Noise) At(x, y float64) float64 {
+
+	if noise.WarpStrength > 0 {
+		frequency := noise.WarpFrequency
+		if frequency == 0 {
+			frequency = noise.Frequency
+		}
+		// Sampling the field at offset positions gives two (mostly) unrelated values to push the position around with.
+		warpX := noise.fractal(x, y, frequency)
+		warpY := noise.fractal(x+52.7, y+13.1, frequency)
+		x += warpX * noise.WarpStrength
+		y += warpY * noise.WarpStrength
+	}
+
+	return noise.fractal(x, y, noise.Frequency)
+
+}
+
+// HeightField is a map of values from 0 to 1 over the cells of a Layout, like elevation; it's used by Layout.CarveRiver() and
+// Layout.CarveRoad().
+type HeightField func(x, y int) float64
+
+// HeightField returns a HeightField that samples the Noise at each cell, with the values mapped from -1 - 1 to 0 - 1.
+func (noise *Noise) HeightField() HeightField {
+	return noise.normalized
+}
+
+// normalized returns the value of the noise field at the given cell, mapped from -1 - 1 to 0 - 1.
+func (noise *Noise) normalized(x, y int) float64 {
+	return math.Max(0, math.Min(1, (noise.At(float64(x), float64(y))+1)/2))
+}
+
+// fractal returns the Noise's octaves added together at the given position, starting at the frequency provided.
+func (noise *Noise) fractal(x, y, frequency float64) float64 {
+
+	total := 0.0
+	amplitude := 1.0
+	maxTotal := 0.0
+
+	for octave := 0; octave < noise.Octaves; octave++ {
+
+		// Offsetting each octave keeps the lattice points of different octaves from lining up.
+		value := noise.sample(x*frequency+float64(octave)*31.7, y*frequency+float64(octave)*17.3)
+
+		if noise.Ridged {
+			value = 1 - math.Abs(value)
+			value *= value
+		}
+
+		total += value * amplitude
+		maxTotal += amplitude
+		amplitude *= noise.Gain
+		frequency *= noise.Lacunarity
+
+	}
+
+	total /= maxTotal
+
+	if noise.Ridged {
+		// Ridged values are between 0 and 1, so they're stretched back out to match the other noise.
+		total = total*2 - 1
+	}
+
+	return total
+
+}
+
+// sample returns a single octave of the Noise's type at the given position.
+func (noise *Noise) sample(x, y float64) float64 {
+	switch noise.Type {
+	case NoisePerlin:
+		return noise.perlin(x, y)
+	case NoiseSimplex:
+		return noise.simplex(x, y)
+	case NoiseOpenSimplex:
+		return noise.openSimplex(x, y)
+	}
+	return noise.value(x, y)
+}
+
+// hash returns a pseudo-random number between 0 and 255 for the lattice point provided.
+func (noise *Noise) hash(x, y int) int {
+	return noise.perm[(noise.perm[x&255]+y)&255]
+}
+
+// fade is Perlin's smootherstep curve, used to ease between lattice points.
+func fade(t float64) float64 {
+	return t * t * t * (t*(t*6-15) + 10)
+}
+
+func lerp(a, b, t float64) float64 {
+	return a + (b-a)*t
+}
+
+// value returns value noise at the given position.
+func (noise *Noise) value(x, y float64) float64 {
+
+	x0, y0 := math.Floor(x), math.Floor(y)
+	xi, yi := int(x0), int(y0)
+	u, v := fade(x-x0), fade(y-y0)
+
+	corner := func(cx, cy int) float64 {
+		return float64(noise.hash(cx, cy))/127.5 - 1
+	}
+
+	return lerp(
+		lerp(corner(xi, yi), corner(xi+1, yi), u),
+		lerp(corner(xi, yi+1), corner(xi+1, yi+1), u),
+		v,
+	)
+
+}
+
+// perlin returns Perlin noise at the given position.
+func (noise *Noise) perlin(x, y float64) float64 {
+
+	x0, y0 := math.Floor(x), math.Floor(y)
+	xi, yi := int(x0), int(y0)
+	xf, yf := x-x0, y-y0
+	u, v := fade(xf), fade(yf)
+
+	gradient := func(hash int, dx, dy float64) float64 {
+		switch hash & 7 {
+		case 0:
+			return dx + dy
+		case 1:
+			return -dx + dy
+		case 2:
+			return dx - dy
+		case 3:
+			return -dx - dy
+		case 4:
+			return dx
+		case 5:
+			return -dx
+		case 6:
+			return dy
+		}
+		return -dy
+	}
+
+	value := lerp(
+		lerp(gradient(noise.hash(xi, yi), xf, yf), gradient(noise.hash(xi+1, yi), xf-1, yf), u),
+		lerp(gradient(noise.hash(xi, yi+1), xf, yf-1), gradient(noise.hash(xi+1, yi+1), xf-1, yf-1), u),
+		v,
+	)
+
+	// 2D Perlin noise with these gradients peaks at about 0.7, so it's scaled up to fill the same range as the other noise.
+	return math.Max(-1, math.Min(1, value*1.4))
+
+}
+
+// The skew and unskew factors for 2D simplex noise.
+var (
+	simplexSkew   = 0.5 * (math.Sqrt(3) - 1)
+	simplexUnskew = (3 - math.Sqrt(3)) / 6
+)
+
+// simplexGradients are the gradients used by simplex noise; the midpoints of the edges of a cube, projected onto 2D.
+var simplexGradients = [12][2]float64{
+	{1, 1}, {-1, 1}, {1, -1}, {-1, -1},
+	{1, 0}, {-1, 0}, {1, 0}, {-1, 0},
+	{0, 1}, {0, -1}, {0, 1}, {0, -1},
+}
+
+// simplex returns simplex noise at the given position.
+func (noise *Noise) simplex(x, y float64) float64 {
+
+	// Skew the input space to find which simplex (triangle) the position is in.
+	s := (x + y) * simplexSkew
+	i, j := math.Floor(x+s), math.Floor(y+s)
+	t := (i + j) * simplexUnskew
+	x0, y0 := x-(i-t), y-(j-t)
+
+	i1, j1 := 0, 1
+	if x0 > y0 {
+		i1, j1 = 1, 0
+	}
+
+	x1, y1 := x0-float64(i1)+simplexUnskew, y0-float64(j1)+simplexUnskew
+	x2, y2 := x0-1+2*simplexUnskew, y0-1+2*simplexUnskew
+
+	ii, jj := int(i), int(j)
+
+	corner := func(hash int, dx, dy float64) float64 {
+		t := 0.5 - dx*dx - dy*dy
+		if t < 0 {
+			return 0
+		}
+		gradient := simplexGradients[hash%12]
+		t *= t
+		return t * t * (gradient[0]*dx + gradient[1]*dy)
+	}
+
+	total := corner(noise.hash(ii, jj), x0, y0) + corner(noise.hash(ii+i1, jj+j1), x1, y1) + corner(noise.hash(ii+1, jj+1), x2, y2)
+
+	return 70 * total
+
+}
+
+// The stretch and squish factors, and the normalization constant, for 2D OpenSimplex noise.
+const (
+	openSimplexStretch = -0.211324865405187 // (1 / sqrt(2 + 1) - 1) / 2
+	openSimplexSquish  = 0.366025403784439  // (sqrt(2 + 1) - 1) / 2
+	openSimplexNorm    = 47.0
+)
+
+// openSimplexGradients are the gradients used by OpenSimplex noise, in pairs of X and Y.
+var openSimplexGradients = [16]float64{
+	5, 2, 2, 5,
+	-5, 2, -2, 5,
+	5, -2, 2, -5,
+	-5, -2, -2, -5,
+}
+
+// openSimplex returns OpenSimplex noise at the given position.
+func (noise *Noise) openSimplex(x, y float64) float64 {
+
+	// Place the input coordinates onto the stretched grid.
+	stretchOffset := (x + y) * openSimplexStretch
+	xs, ys := x+stretchOffset, y+stretchOffset
+
+	xsb, ysb := int(math.Floor(xs)), int(math.Floor(ys))
+
+	// Skew the rhombus's origin back out to find its position in the input space.
+	squishOffset := float64(xsb+ysb) * openSimplexSquish
+	xb, yb := float64(xsb)+squishOffset, float64(ysb)+squishOffset
+
+	xins, yins := xs-float64(xsb), ys-float64(ysb)
+	inSum := xins + yins
+
+	dx0, dy0 := x-xb, y-yb
+
+	extrapolate := func(xsv, ysv int, dx, dy float64) float64 {
+		index := noise.hash(xsv, ysv) & 0x0E
+		return openSimplexGradients[index]*dx + openSimplexGradients[index+1]*dy
+	}
+
+	contribute := func(xsv, ysv int, dx, dy float64) float64 {
+		attenuation := 2 - dx*dx - dy*dy
+		if attenuation <= 0 {
+			return 0
+		}
+		attenuation *= attenuation
+		return attenuation * attenuation * extrapolate(xsv, ysv, dx, dy)
+	}
+
+	value := contribute(xsb+1, ysb, dx0-1-openSimplexSquish, dy0-openSimplexSquish)
+	value += contribute(xsb, ysb+1, dx0-openSimplexSquish, dy0-1-openSimplexSquish)
+
+	var xsvExt, ysvExt int
+	var dxExt, dyExt float64
+
+	if inSum <= 1 {
+
+		// Inside the triangle at (0, 0).
+		zins := 1 - inSum
+		if zins > xins || zins > yins {
+			if xins > yins {
+				xsvExt, ysvExt = xsb+1, ysb-1
+				dxExt, dyExt = dx0-1, dy0+1
+			} else {
+				xsvExt, ysvExt = xsb-1, ysb+1
+				dxExt, dyExt = dx0+1, dy0-1
+			}
+		} else {
+			xsvExt, ysvExt = xsb+1, ysb+1
+			dxExt, dyExt = dx0-1-2*openSimplexSquish, dy0-1-2*openSimplexSquish
+		}
+
+	} else {
+
+		// Inside the triangle at (1, 1).
+		zins := 2 - inSum
+		if zins < xins || zins < yins {
+			if xins > yins {
+				xsvExt, ysvExt = xsb+2, ysb
+				dxExt, dyExt = dx0-2-2*openSimplexSquish, dy0-2*openSimplexSquish
+			} else {
+				xsvExt, ysvExt = xsb, ysb+2
+				dxExt, dyExt = dx0-2*openSimplexSquish, dy0-2-2*openSimplexSquish
+			}
+		} else {
+			xsvExt, ysvExt = xsb, ysb
+			dxExt, dyExt = dx0, dy0
+		}
+
+		xsb++
+		ysb++
+		dx0 -= 1 + 2*openSimplexSquish
+		dy0 -= 1 + 2*openSimplexSquish
+
+	}
+
+	value += contribute(xsb, ysb, dx0, dy0)
+	value += contribute(xsvExt, ysvExt, dxExt, dyExt)
+
+	return value / openSimplexNorm
+
+}
+
+// NoiseBand is a band of noise values that's drawn as a single rune by Layout.GenerateFromNoise(). Max holds the highest value
+// (from 0 to 1) of each noise field that falls into the band; fields without a Max entry aren't limited.
+type NoiseBand struct {
+	Value rune
+	Max   []float64
+}
+
+// NewDefaultNoiseBands returns NoiseBands for simple overworld terrain from a single elevation field: water ('~') below 0.4,
+// sand ('.') below 0.45, grass (' ') below 0.7, and mountains ('^') above that.
+func NewDefaultNoiseBands() []NoiseBand {
+	return []NoiseBand{
+		{Value: '~', Max: []float64{0.4}},
+		{Value: '.', Max: []float64{0.45}},
+		{Value: ' ', Max: []float64{0.7}},
+		{Value: '^', Max: []float64{1}},
+	}
+}
+
+// GenerateFromNoise fills the Layout by sampling one or more noise fields at each cell (elevation and moisture, for example), and
+// drawing the cell with the first band that the values fall into. Noise values are mapped from -1 - 1 to 0 - 1 before being
+// compared to the bands' Max values. For example, with the bands from NewDefaultNoiseBands(), cells with an elevation of 0.42 are
+// drawn as sand, since 0.42 is above the water band's Max of 0.4, but below the sand band's Max of 0.45. Cells that don't fall
+// into any band are left as they are.
+// GenerateFromNoise returns a Selection of the cells drawn with each band (in the same order as the bands), or an error if no
+// fields or bands are given, or one of the fields' settings is invalid. Since the noise fields are seeded when they're created
+// (see NewNoise()), GenerateFromNoise doesn't use the Layout's RNG.
+// GenerateFromNoise is the same as GenerateFromNoiseContext() with a background context.
+func (layout *Layout) GenerateFromNoise(fields []*Noise, bands []NoiseBand) ([]Selection, error) {
+	return layout.GenerateFromNoiseContext(context.Background(), fields, bands)
+}
+
+// GenerateFromNoiseContext works like GenerateFromNoise(), but stops and returns an error if the context is cancelled or the
+// Layout's StepBudget runs out before generation finishes.
+func (layout *Layout) GenerateFromNoiseContext(ctx context.Context, fields []*Noise, bands []NoiseBand) ([]Selection, error) {
+
+	if err := layout.validateSize("GenerateFromNoise"); err != nil {
+		return nil, err
+	}
+
+	if len(fields) == 0 {
+		return nil, invalidArgument("GenerateFromNoise", "no noise fields given")
+	}
+
+	if len(bands) == 0 {
+		return nil, invalidArgument("GenerateFromNoise", "no bands given")
+	}
+
+	for _, field := range fields {
+		if field == nil {
+			return nil, invalidArgument("GenerateFromNoise", "noise field is nil")
+		}
+		if err := field.validate("GenerateFromNoise"); err != nil {
+			return nil, err
+		}
+	}
+
+	gen := layout.generation(ctx, "noise")
+
+	selections := make([]Selection, len(bands))
+	for i := range selections {
+		selections[i] = Selection{Layout: layout, Cells: map[Position]bool{}}
+	}
+
+	values := make([]float64, len(fields))
+
+	for y := 0; y < layout.Height; y++ {
+
+		for x := 0; x < layout.Width; x++ {
+
+			for i, field := range fields {
+				values[i] = field.normalized(x, y)
+			}
+
+			if band := noiseBandFor(bands, values); band >= 0 {
+				layout.Set(x, y, bands[band].Value)
+				selections[band].Cells[Position{x, y}] = true
+			}
+
+		}
+
+		if err := gen.step(GenerationStep{Kind: StepFill, X: 0, Y: y, W: layout.Width, H: 1}); err != nil {
+			return nil, err
+		}
+
+	}
+
+	return selections, nil
+
+}
+
+// noiseBandFor returns the index of the first band the values fall into, or -1 if they don't fall into any of them.
+func noiseBandFor(bands []NoiseBand, values []float64) int {
+
+	for i, band := range bands {
+
+		inBand := true
+
+		for field, max := range band.Max {
+			if field < len(values) && values[field] > max {
+				inBand = false
+				break
+			}
+		}
+
+		if inBand {
+			return i
+		}
+
+	}
+
+	return -1
+
+}
@@ -0,0 +1,78 @@
+package dngn
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+// TestNoise checks that every type of noise draws the same map from the same seed, and that the default bands cover every cell.
+func TestNoise(t *testing.T) {
+
+	generate := func(noiseType NoiseType, seed int64) (*Layout, []Selection) {
+
+		layout := NewLayout(60, 40)
+
+		elevation := NewNoise(noiseType, seed)
+		elevation.Octaves = 3
+		elevation.WarpStrength = 4
+
+		selections, err := layout.GenerateFromNoise([]*Noise{elevation}, NewDefaultNoiseBands())
+		if err != nil {
+			t.Fatalf("%s, seed %d: %v", noiseType, seed, err)
+		}
+
+		return layout, selections
+
+	}
+
+	for _, noiseType := range []NoiseType{NoiseValue, NoisePerlin, NoiseSimplex, NoiseOpenSimplex} {
+
+		first, selections := generate(noiseType, 1)
+		second, _ := generate(noiseType, 1)
+		other, _ := generate(noiseType, 2)
+
+		if first.DataToString() != second.DataToString() {
+			t.Errorf("%s: the same seed drew different maps", noiseType)
+		}
+
+		if first.DataToString() == other.DataToString() {
+			t.Errorf("%s: different seeds drew the same map", noiseType)
+		}
+
+		cells := 0
+		for _, selection := range selections {
+			cells += len(selection.Cells)
+		}
+
+		if cells != first.Area() {
+			t.Errorf("%s: the bands cover %d cells out of %d", noiseType, cells, first.Area())
+		}
+
+	}
+
+}
+
+// TestNoiseInvalid checks that noise settings that can't be used (including NaN) are rejected.
+func TestNoiseInvalid(t *testing.T) {
+
+	for name, set := range map[string]func(noise *Noise){
+		"frequency of 0":          func(noise *Noise) { noise.Frequency = 0 },
+		"frequency of NaN":        func(noise *Noise) { noise.Frequency = math.NaN() },
+		"gain of NaN":             func(noise *Noise) { noise.Gain = math.NaN() },
+		"warp strength of NaN":    func(noise *Noise) { noise.WarpStrength = math.NaN() },
+		"no octaves":              func(noise *Noise) { noise.Octaves = 0 },
+		"unknown type":            func(noise *Noise) { noise.Type = NoiseOpenSimplex + 1 },
+		"negative warp frequency": func(noise *Noise) { noise.WarpFrequency = -1 },
+	} {
+
+		noise := NewNoise(NoisePerlin, 1)
+		set(noise)
+
+		if _, err := NewLayout(20, 20).GenerateFromNoise([]*Noise{noise}, NewDefaultNoiseBands()); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("%s: expected ErrInvalidArgument, got %v", name, err)
+		}
+
+	}
+
+}
@@ -0,0 +1,450 @@
+package dngn
+
+import (
+	"container/heap"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// ErrNoPath is returned (wrapped) by Layout.CarveRoad() when there's no way to get from the start to the end.
+var ErrNoPath = errors.New("no path found")
+
+// WidthProfile describes how wide a carved path is along its length, going smoothly from Start (at the start of the path) to End
+// (at the end of the path). Rivers, for example, usually start narrow and widen as they go.
+type WidthProfile struct {
+	Start, End float64
+}
+
+// at returns the width of the path at the given point along it, from 0 to 1.
+func (profile WidthProfile) at(t float64) float64 {
+	return profile.Start + (profile.End-profile.Start)*t
+}
+
+// CarvedPath is a path carved through a Layout by Layout.CarveRiver() or Layout.CarveRoad().
+type CarvedPath struct {
+	Path    []Position // The center line of the path, from start to end.
+	Cells   Selection  // Every cell painted by the path.
+	Bridges Selection  // The cells where a road was painted as a bridge, over water; this is empty for rivers.
+}
+
+// RiverOptions configures Layout.CarveRiver().
+type RiverOptions struct {
+	Value  rune         // The rune the river is painted with.
+	Height HeightField  // The height field the river flows down; this is required.
+	Width  WidthProfile // How wide the river is, from its source to its end.
+
+	// The river ends when it flows into a cell with one of these runes (like an ocean, or another river), or when it reaches the
+	// edge of the Layout.
+	StopValues []rune
+
+	// How much (in height) the river can randomly stray from the steepest way down each step; higher values make it wind around
+	// more. This uses the Layout's RNG.
+	Meander float64
+
+	MaxLength int // The most cells long the river can be; if 0, it's only limited by the size of the Layout.
+}
+
+// NewDefaultRiverOptions returns RiverOptions for a river drawn with '~' that flows down the height field provided into the ocean
+// (or another river), and widens from 1 to 3 cells across as it goes.
+func NewDefaultRiverOptions(height HeightField) RiverOptions {
+	return RiverOptions{
+		Value:      '~',
+		Height:     height,
+		Width:      WidthProfile{1, 3},
+		StopValues: []rune{'~'},
+	}
+}
+
+// CarveRiver traces a river from the source provided, flowing downhill on the height field given in the options one cell at a time
+// (in cardinal directions, so that the river can't be crossed diagonally, or from hex to hex on hex grids), and then paints it onto
+// the Layout. When the river runs into a pit with no lower neighbors, it keeps going through the lowest neighbor it hasn't visited
+// yet, as a real river would after filling up a lake. It returns the carved river, or an error if the source is outside of the
+// Layout or the options are invalid.
+func (layout *Layout) CarveRiver(source Position, options RiverOptions) (*CarvedPath, error) {
+
+	if !layout.inBounds(source) {
+		return nil, invalidArgument("CarveRiver", "source %v is outside of the %dx%d layout", source, layout.Width, layout.Height)
+	}
+
+	if options.Height == nil {
+		return nil, invalidArgument("CarveRiver", "no height field given")
+	}
+
+	// These are written so that NaN fails them too.
+	if !(options.Meander >= 0) || options.MaxLength < 0 {
+		return nil, invalidArgument("CarveRiver", "meander and max length can't be negative, got %v and %d", options.Meander, options.MaxLength)
+	}
+
+	if !(options.Width.Start >= 0 && options.Width.End >= 0) {
+		return nil, invalidArgument("CarveRiver", "width can't be negative, got %v", options.Width)
+	}
+
+	stops := map[rune]bool{}
+	for _, value := range options.StopValues {
+		stops[value] = true
+	}
+
+	maxLength := options.MaxLength
+	if maxLength == 0 {
+		maxLength = layout.Area()
+	}
+
+	path := []Position{source}
+	visited := map[Position]bool{source: true}
+	current := source
+
+	for len(path) < maxLength {
+
+		if current.X == 0 || current.Y == 0 || current.X == layout.Width-1 || current.Y == layout.Height-1 {
+			break
+		}
+
+		if len(path) > 1 && stops[layout.Get(current.X, current.Y)] {
+			break
+		}
+
+		next := Position{-1, -1}
+		lowest := math.MaxFloat64
+
+		for _, side := range layout.pathSides(current) {
+
+			if !layout.inBounds(side) || visited[side] {
+				continue
+			}
+
+			height := options.Height(side.X, side.Y)
+			if options.Meander > 0 {
+				height += layout.RNG.Float64() * options.Meander
+			}
+
+			if height < lowest {
+				lowest = height
+				next = side
+			}
+
+		}
+
+		if next.X < 0 {
+			break
+		}
+
+		path = append(path, next)
+		visited[next] = true
+		current = next
+
+	}
+
+	result := &CarvedPath{
+		Path:    path,
+		Cells:   layout.paintPath(path, options.Width, options.Value),
+		Bridges: Selection{Layout: layout, Cells: map[Position]bool{}},
+	}
+
+	return result, nil
+
+}
+
+// RoadOptions configures Layout.CarveRoad().
+type RoadOptions struct {
+	Value       rune         // The rune the road is painted with.
+	BridgeValue rune         // The rune the road is painted with where it crosses water.
+	Width       WidthProfile // How wide the road is, from start to end.
+
+	// The runes the road has to bridge over (like rivers); cells with these runes are painted with BridgeValue instead of Value.
+	WaterValues []rune
+
+	// The cost of the road going through a cell with each rune; runes that aren't in the map cost DefaultCost. Negative costs
+	// mean the road can't go through those cells at all.
+	Costs       map[rune]float64
+	DefaultCost float64
+
+	// An optional height field; if set, the road avoids steep slopes, with each step costing SlopeCost times the change in height
+	// (from 0 to 1) on top of the usual cost.
+	Height    HeightField
+	SlopeCost float64
+
+	// How much random cost (from 0 up to Meander) is added to each cell, making the road wind around instead of going in straight
+	// lines. The random costs come from noise seeded with the Layout's RNG, so the road curves smoothly.
+	Meander float64
+}
+
+// NewDefaultRoadOptions returns RoadOptions for a single-cell road drawn with '=', which bridges ('#') rivers ('~') where it has to,
+// avoids mountains ('^') and slopes, and winds around a little.
+func NewDefaultRoadOptions() RoadOptions {
+	return RoadOptions{
+		Value:       '=',
+		BridgeValue: '#',
+		Width:       WidthProfile{1, 1},
+		WaterValues: []rune{'~'},
+		Costs:       map[rune]float64{'~': 8, '^': 6},
+		DefaultCost: 1,
+		SlopeCost:   20,
+		Meander:     2,
+	}
+}
+
+// CarveRoad finds the cheapest path from start to end using A* search, where each cell's cost is set through the options (see
+// RoadOptions), and then paints it onto the Layout as a road, painting bridges where it crosses water. Like rivers, roads go in
+// cardinal directions, or from hex to hex on hex grids. It returns the carved road, or an error wrapping ErrNoPath if the end can't
+// be reached, or ErrInvalidArgument if start or end are outside of the Layout or the options are invalid.
+func (layout *Layout) CarveRoad(start, end Position, options RoadOptions) (*CarvedPath, error) {
+
+	if !layout.inBounds(start) || !layout.inBounds(end) {
+		return nil, invalidArgument("CarveRoad", "start %v and end %v must be inside of the %dx%d layout", start, end, layout.Width, layout.Height)
+	}
+
+	// These are written so that NaN fails them too.
+	if !(options.DefaultCost >= 0 && options.SlopeCost >= 0 && options.Meander >= 0) {
+		return nil, invalidArgument("CarveRoad", "default cost, slope cost, and meander can't be negative")
+	}
+
+	if !(options.Width.Start >= 0 && options.Width.End >= 0) {
+		return nil, invalidArgument("CarveRoad", "width can't be negative, got %v", options.Width)
+	}
+
+	var meander *Noise
+	if options.Meander > 0 {
+		meander = NewNoise(NoiseSimplex, layout.RNG.Int63())
+		meander.Frequency = 0.15
+	}
+
+	cost := func(from, to Position) float64 {
+
+		c, exists := options.Costs[layout.Get(to.X, to.Y)]
+		if !exists {
+			c = options.DefaultCost
+		}
+
+		if c < 0 {
+			return c
+		}
+
+		if options.Height != nil && options.SlopeCost > 0 {
+			c += math.Abs(options.Height(to.X, to.Y)-options.Height(from.X, from.Y)) * options.SlopeCost
+		}
+
+		if meander != nil {
+			c += meander.normalized(to.X, to.Y) * options.Meander
+		}
+
+		return c
+
+	}
+
+	// The cheapest a step can possibly be, so the A* heuristic never overestimates the cost of getting to the end.
+	minCost := options.DefaultCost
+	for _, c := range options.Costs {
+		if c >= 0 && c < minCost {
+			minCost = c
+		}
+	}
+
+	heuristic := func(position Position) float64 {
+		return float64(layout.GridDistance(position, end, false)) * minCost
+	}
+
+	from := map[Position]Position{}
+	costSoFar := map[Position]float64{start: 0}
+	open := &pathQueue{}
+	heap.Push(open, pathNode{start, heuristic(start), 0})
+	order := 1
+
+	for open.Len() > 0 {
+
+		node := heap.Pop(open).(pathNode)
+		current := node.Position
+
+		if current == end {
+			break
+		}
+
+		if node.priority > costSoFar[current]+heuristic(current) {
+			continue // An outdated entry; the cell was already reached more cheaply.
+		}
+
+		for _, side := range layout.pathSides(current) {
+
+			if !layout.inBounds(side) {
+				continue
+			}
+
+			step := cost(current, side)
+			if step < 0 {
+				continue
+			}
+
+			newCost := costSoFar[current] + step
+
+			if existing, visited := costSoFar[side]; !visited || newCost < existing {
+				costSoFar[side] = newCost
+				from[side] = current
+				heap.Push(open, pathNode{side, newCost + heuristic(side), order})
+				order++
+			}
+
+		}
+
+	}
+
+	if _, reached := costSoFar[end]; !reached {
+		return nil, fmt.Errorf("dngn: CarveRoad: can't get from %v to %v: %w", start, end, ErrNoPath)
+	}
+
+	path := []Position{end}
+	for cell := end; cell != start; {
+		cell = from[cell]
+		path = append([]Position{cell}, path...)
+	}
+
+	water := map[rune]bool{}
+	for _, value := range options.WaterValues {
+		water[value] = true
+	}
+
+	// Find the water cells under the road before painting it, as the road covers them up.
+	bridgeCells := map[Position]bool{}
+	for cell := range layout.pathCells(path, options.Width) {
+		if water[layout.Get(cell.X, cell.Y)] {
+			bridgeCells[cell] = true
+		}
+	}
+
+	result := &CarvedPath{
+		Path:    path,
+		Cells:   layout.paintPath(path, options.Width, options.Value),
+		Bridges: Selection{Layout: layout, Cells: bridgeCells},
+	}
+
+	result.Bridges.Fill(options.BridgeValue)
+
+	return result, nil
+
+}
+
+// pathCells returns the cells covered by the path provided when it's painted with the given width profile; each cell along the
+// path covers a circle as wide as the path is at that point.
+func (layout *Layout) pathCells(path []Position, width WidthProfile) map[Position]bool {
+
+	cells := map[Position]bool{}
+
+	for i, center := range path {
+
+		t := 0.0
+		if len(path) > 1 {
+			t = float64(i) / float64(len(path)-1)
+		}
+
+		radius := (width.at(t) - 1) / 2
+		reach := int(math.Ceil(radius))
+
+		for y := center.Y - reach; y <= center.Y+reach; y++ {
+			for x := center.X - reach; x <= center.X+reach; x++ {
+				cell := Position{x, y}
+				if layout.inBounds(cell) && cell.DistanceTo(center) <= radius+0.5 {
+					cells[cell] = true
+				}
+			}
+		}
+
+	}
+
+	return cells
+
+}
+
+// paintPath paints the path provided onto the Layout with the given width profile and value, returning the painted cells.
+func (layout *Layout) paintPath(path []Position, width WidthProfile, value rune) Selection {
+	selection := Selection{Layout: layout, Cells: layout.pathCells(path, width)}
+	selection.Fill(value)
+	return selection
+}
+
+// FindPath returns the shortest path from start to end (including both), moving from neighbor to neighbor through the cells that
+// passable returns true for; neighbors follow the Layout's Grid (see Layout.Neighbors()), and diagonal only applies to square grids.
+// If there's no way to get to the end, or either position is outside of the Layout, FindPath returns nil.
+func (layout *Layout) FindPath(start, end Position, diagonal bool, passable func(x, y int) bool) []Position {
+
+	if !layout.inBounds(start) || !layout.inBounds(end) {
+		return nil
+	}
+
+	from := map[Position]Position{start: start}
+	toVisit := []Position{start}
+
+	for len(toVisit) > 0 {
+
+		current := toVisit[0]
+		toVisit = toVisit[1:]
+
+		if current == end {
+			break
+		}
+
+		for _, side := range layout.Neighbors(current.X, current.Y, diagonal) {
+			if _, visited := from[side]; !visited && layout.inBounds(side) && passable(side.X, side.Y) {
+				from[side] = current
+				toVisit = append(toVisit, side)
+			}
+		}
+
+	}
+
+	if _, reached := from[end]; !reached {
+		return nil
+	}
+
+	path := []Position{end}
+	for cell := end; cell != start; {
+		cell = from[cell]
+		path = append(path, cell)
+	}
+
+	for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+		path[i], path[j] = path[j], path[i]
+	}
+
+	return path
+
+}
+
+// pathSides returns the cells a river or road can flow or go to from the position provided: the cells in the cardinal directions on
+// square grids, or the 6 hexes around it on hex grids.
+func (layout *Layout) pathSides(position Position) []Position {
+	if layout.Grid.IsHex() {
+		return layout.Neighbors(position.X, position.Y, false)
+	}
+	return []Position{{position.X + 1, position.Y}, {position.X - 1, position.Y}, {position.X, position.Y + 1}, {position.X, position.Y - 1}}
+}
+
+// inBounds returns if the position is inside of the Layout.
+func (layout *Layout) inBounds(position Position) bool {
+	return position.X >= 0 && position.Y >= 0 && position.X < layout.Width && position.Y < layout.Height
+}
+
+// pathNode is a cell waiting to be visited in CarveRoad()'s A* search. Order is when the node was added, to break ties between
+// nodes with the same priority consistently.
+type pathNode struct {
+	Position
+	priority float64
+	order    int
+}
+
+// pathQueue is a priority queue of pathNodes, with the lowest priority first.
+type pathQueue []pathNode
+
+func (queue pathQueue) Len() int { return len(queue) }
+func (queue pathQueue) Less(i, j int) bool {
+	if queue[i].priority != queue[j].priority {
+		return queue[i].priority < queue[j].priority
+	}
+	return queue[i].order < queue[j].order
+}
+func (queue pathQueue) Swap(i, j int)          { queue[i], queue[j] = queue[j], queue[i] }
+func (queue *pathQueue) Push(node interface{}) { *queue = append(*queue, node.(pathNode)) }
+func (queue *pathQueue) Pop() interface{} {
+	old := *queue
+	node := old[len(old)-1]
+	*queue = old[:len(old)-1]
+	return node
+}
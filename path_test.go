@@ -0,0 +1,107 @@
+package dngn
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// TestCarveRoad checks that a road bridges the river between its ends, and that a wall the road can't go through gives ErrNoPath.
+func TestCarveRoad(t *testing.T) {
+
+	layout := NewLayout(30, 20)
+	layout.RNG = rand.New(rand.NewSource(1))
+	layout.Select().Fill(' ')
+	layout.DrawLine(15, 0, 15, 19, '~', 1, false)
+
+	road, err := layout.CarveRoad(Position{2, 10}, Position{27, 10}, NewDefaultRoadOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if road.Path[0] != (Position{2, 10}) || road.Path[len(road.Path)-1] != (Position{27, 10}) {
+		t.Errorf("the road goes from %v to %v", road.Path[0], road.Path[len(road.Path)-1])
+	}
+
+	for i := 1; i < len(road.Path); i++ {
+		if distance := layout.GridDistance(road.Path[i-1], road.Path[i], false); distance != 1 {
+			t.Errorf("%v and %v are %d steps apart along the road", road.Path[i-1], road.Path[i], distance)
+		}
+	}
+
+	if len(road.Bridges.Cells) == 0 {
+		t.Errorf("the road crosses the river without a bridge")
+	}
+
+	for cell := range road.Bridges.Cells {
+		if cell.X != 15 {
+			t.Errorf("the bridge at %v isn't over the river", cell)
+		}
+	}
+
+	// Mountains that can't be crossed at all split the map in two.
+	options := NewDefaultRoadOptions()
+	options.Costs['^'] = -1
+	layout.DrawLine(5, 0, 5, 19, '^', 1, false)
+
+	if _, err := layout.CarveRoad(Position{2, 10}, Position{27, 10}, options); !errors.Is(err, ErrNoPath) {
+		t.Errorf("expected ErrNoPath through an impassable wall, got %v", err)
+	}
+
+	options = NewDefaultRoadOptions()
+	options.Meander = math.NaN()
+
+	if _, err := layout.CarveRoad(Position{2, 10}, Position{27, 10}, options); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument with a NaN meander, got %v", err)
+	}
+
+}
+
+// TestCarveRiver checks that a river flows downhill to the edge of the Layout, and that the same seed carves the same river.
+func TestCarveRiver(t *testing.T) {
+
+	generate := func(seed int64) (*Layout, *CarvedPath) {
+
+		layout := NewLayout(40, 30)
+		layout.RNG = rand.New(rand.NewSource(seed))
+		layout.Select().Fill(' ')
+
+		// The land slopes down to the right.
+		height := func(x, y int) float64 { return 1 - float64(x)/40 }
+
+		options := NewDefaultRiverOptions(height)
+		options.Meander = 0.05
+
+		river, err := layout.CarveRiver(Position{5, 15}, options)
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+
+		return layout, river
+
+	}
+
+	for seed := int64(1); seed <= 3; seed++ {
+
+		first, river := generate(seed)
+		second, _ := generate(seed)
+
+		if first.DataToString() != second.DataToString() {
+			t.Errorf("seed %d: the same seed carved different rivers", seed)
+		}
+
+		if end := river.Path[len(river.Path)-1]; end.X != first.Width-1 && end.Y != 0 && end.Y != first.Height-1 {
+			t.Errorf("seed %d: the river ends at %v, rather than at the edge of the Layout", seed, end)
+		}
+
+	}
+
+	options := NewDefaultRiverOptions(func(x, y int) float64 { return 0 })
+	options.Meander = math.NaN()
+
+	if _, err := NewLayout(20, 20).CarveRiver(Position{10, 10}, options); !errors.Is(err, ErrInvalidArgument) {
+		t.Errorf("expected ErrInvalidArgument with a NaN meander, got %v", err)
+	}
+
+}
@@ -0,0 +1,1084 @@
+package dngn
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// A Pipeline is a recipe for generating a Layout: a list of PipelineSteps that are run one after another on the same Layout, using
+// the same seeded RNG. Because every step draws from the Layout's RNG, running a Pipeline again with the same seed produces the
+// same Layout.
+// Pipelines can be built in Go, or loaded from JSON or YAML recipes using ReadPipeline() (and saved to JSON recipes using
+// json.Marshal()), which makes them easy to share between tools. A recipe looks like this:
+//
+//	{
+//		"width": 80,
+//		"height": 45,
+//		"seed": 42,
+//		"steps": [
+//			{ "type": "generate", "generator": "bsp", "splitCount": 60, "minimumRoomSize": 3 },
+//			{ "type": "select", "filters": [ { "type": "border", "distance": 1 } ], "fill": "x" },
+//			{ "type": "select", "filters": [ { "type": "rune", "rune": " " }, { "type": "percentage", "percentage": 0.1 } ], "fill": "." },
+//			{ "type": "validate", "floor": " ", "connected": true }
+//		]
+//	}
+//
+// Or, in YAML:
+//
+//	width: 80
+//	height: 45
+//	seed: 42
+//	steps:
+//	  - { type: generate, generator: bsp, splitCount: 60, minimumRoomSize: 3 }
+//	  - { type: select, filters: [ { type: border, distance: 1 } ], fill: "x" }
+//	  - { type: select, filters: [ { type: rune, rune: " " }, { type: percentage, percentage: 0.1 } ], fill: "." }
+//	  - { type: validate, floor: " ", connected: true }
+//
+// Runes in YAML recipes should be quoted, since YAML reads some letters on their own (like y and n) as true and false.
+type Pipeline struct {
+	Width, Height int            // Size of the Layout created by Pipeline.Generate().
+	Seed          int64          // Seed used by Pipeline.Generate().
+	Grid          GridType       // Grid of the Layout created by Pipeline.Generate(); "grid" in recipes (see GridType.String()).
+	Steps         []PipelineStep // The steps to run, in order.
+}
+
+// PipelineStep is a single step in a Pipeline. Apply runs the step on the given Layout, returning an error if the step failed.
+type PipelineStep interface {
+	Apply(layout *Layout) error
+}
+
+// ErrValidation is returned (wrapped) by a ValidateStep when the Layout doesn't pass validation. Use errors.Is() to check for it, and
+// run the Pipeline again with a different seed to try again.
+var ErrValidation = errors.New("validation failed")
+
+// NewPipeline returns a new Pipeline of the given size, seed, and steps.
+func NewPipeline(width, height int, seed int64, steps ...PipelineStep) *Pipeline {
+	return &Pipeline{
+		Width:  width,
+		Height: height,
+		Seed:   seed,
+		Steps:  steps,
+	}
+}
+
+// ReadPipeline reads a JSON or YAML recipe from the provided Reader and returns the Pipeline it describes. Recipes that start with
+// "{" are read as JSON, and anything else as YAML.
+func ReadPipeline(reader io.Reader) (*Pipeline, error) {
+
+	data, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	if trimmed := bytes.TrimSpace(data); len(trimmed) == 0 || trimmed[0] != '{' {
+		if data, err = yamlToJSON(data); err != nil {
+			return nil, err
+		}
+	}
+
+	pipeline := &Pipeline{}
+
+	if err := json.Unmarshal(data, pipeline); err != nil {
+		return nil, err
+	}
+
+	return pipeline, nil
+
+}
+
+// yamlToJSON converts a YAML recipe to JSON, so that it can be decoded the same way as a JSON recipe.
+func yamlToJSON(data []byte) ([]byte, error) {
+
+	var recipe interface{}
+
+	if err := yaml.Unmarshal(data, &recipe); err != nil {
+		return nil, err
+	}
+
+	return json.Marshal(jsonValue(recipe))
+
+}
+
+// jsonValue returns the value decoded from YAML provided with its maps' keys turned into strings, which JSON needs.
+func jsonValue(value interface{}) interface{} {
+
+	switch value := value.(type) {
+
+	case map[interface{}]interface{}:
+		object := map[string]interface{}{}
+		for key, element := range value {
+			object[fmt.Sprint(key)] = jsonValue(element)
+		}
+		return object
+
+	case []interface{}:
+		array := make([]interface{}, len(value))
+		for i, element := range value {
+			array[i] = jsonValue(element)
+		}
+		return array
+
+	}
+
+	return value
+
+}
+
+// Add adds the steps provided to the end of the Pipeline, returning the Pipeline so calls can be chained.
+func (pipeline *Pipeline) Add(steps ...PipelineStep) *Pipeline {
+	pipeline.Steps = append(pipeline.Steps, steps...)
+	return pipeline
+}
+
+// Generate creates a new Layout of the Pipeline's size and runs the Pipeline on it using the Pipeline's seed.
+func (pipeline *Pipeline) Generate() (*Layout, error) {
+
+	if pipeline.Width <= 0 || pipeline.Height <= 0 {
+		return nil, fmt.Errorf("dngn: pipeline size must be positive, got %dx%d", pipeline.Width, pipeline.Height)
+	}
+
+	layout := NewLayout(pipeline.Width, pipeline.Height)
+	layout.Grid = pipeline.Grid
+
+	if err := pipeline.Run(layout, pipeline.Seed); err != nil {
+		return nil, err
+	}
+
+	return layout, nil
+
+}
+
+// Run seeds the Layout's RNG with the seed provided and then applies each of the Pipeline's steps to the Layout in order. If a step
+// fails, Run stops and returns the error.
+func (pipeline *Pipeline) Run(layout *Layout, seed int64) error {
+
+	layout.SetRNG(rand.NewSource(seed))
+
+	for i, step := range pipeline.Steps {
+		if err := step.Apply(layout); err != nil {
+			return fmt.Errorf("dngn: pipeline step %d: %w", i, err)
+		}
+	}
+
+	return nil
+
+}
+
+// pipelineSteps maps the "type" of each step in a JSON recipe to a function that decodes it.
+var pipelineSteps = map[string]func(data []byte) (PipelineStep, error){
+	"generate": func(data []byte) (PipelineStep, error) {
+		step := GenerateStep{}
+		err := json.Unmarshal(data, &step)
+		return step, err
+	},
+	"select": func(data []byte) (PipelineStep, error) {
+		step := SelectStep{}
+		err := json.Unmarshal(data, &step)
+		return step, err
+	},
+	"connect": func(data []byte) (PipelineStep, error) {
+		step := ConnectStep{}
+		err := json.Unmarshal(data, &step)
+		return step, err
+	},
+	"prefab": func(data []byte) (PipelineStep, error) {
+		step := PrefabStep{}
+		err := json.Unmarshal(data, &step)
+		return step, err
+	},
+	"noise": func(data []byte) (PipelineStep, error) {
+		step := NoiseStep{}
+		err := json.Unmarshal(data, &step)
+		return step, err
+	},
+	"biomes": func(data []byte) (PipelineStep, error) {
+		step := BiomeStep{}
+		err := json.Unmarshal(data, &step)
+		return step, err
+	},
+	"validate": func(data []byte) (PipelineStep, error) {
+		step := ValidateStep{}
+		err := json.Unmarshal(data, &step)
+		return step, err
+	},
+}
+
+// pipelineStepType returns the recipe "type" of the step provided, or an empty string if the step can't be saved in a recipe.
+func pipelineStepType(step PipelineStep) string {
+	switch step.(type) {
+	case GenerateStep, *GenerateStep:
+		return "generate"
+	case SelectStep, *SelectStep:
+		return "select"
+	case ConnectStep, *ConnectStep:
+		return "connect"
+	case PrefabStep, *PrefabStep:
+		return "prefab"
+	case NoiseStep, *NoiseStep:
+		return "noise"
+	case BiomeStep, *BiomeStep:
+		return "biomes"
+	case ValidateStep, *ValidateStep:
+		return "validate"
+	}
+	return ""
+}
+
+type pipelineRecipe struct {
+	Width  int               `json:"width"`
+	Height int               `json:"height"`
+	Seed   int64             `json:"seed"`
+	Grid   string            `json:"grid,omitempty"`
+	Steps  []json.RawMessage `json:"steps"`
+}
+
+// MarshalJSON encodes the Pipeline as a JSON recipe. Steps that can't be described in a recipe (like FuncSteps) cause an error.
+func (pipeline Pipeline) MarshalJSON() ([]byte, error) {
+
+	recipe := pipelineRecipe{
+		Width:  pipeline.Width,
+		Height: pipeline.Height,
+		Seed:   pipeline.Seed,
+		Steps:  []json.RawMessage{},
+	}
+
+	if pipeline.Grid != GridSquare {
+		recipe.Grid = pipeline.Grid.String()
+	}
+
+	for i, step := range pipeline.Steps {
+
+		stepType := pipelineStepType(step)
+
+		if stepType == "" {
+			return nil, fmt.Errorf("dngn: pipeline step %d (%T) can't be saved in a recipe", i, step)
+		}
+
+		data, err := json.Marshal(step)
+		if err != nil {
+			return nil, err
+		}
+
+		fields := map[string]json.RawMessage{}
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, err
+		}
+
+		fields["type"], _ = json.Marshal(stepType)
+
+		if data, err = json.Marshal(fields); err != nil {
+			return nil, err
+		}
+
+		recipe.Steps = append(recipe.Steps, data)
+
+	}
+
+	return json.Marshal(recipe)
+
+}
+
+// UnmarshalJSON decodes a JSON recipe into the Pipeline.
+func (pipeline *Pipeline) UnmarshalJSON(data []byte) error {
+
+	recipe := pipelineRecipe{}
+
+	if err := json.Unmarshal(data, &recipe); err != nil {
+		return err
+	}
+
+	pipeline.Width = recipe.Width
+	pipeline.Height = recipe.Height
+	pipeline.Seed = recipe.Seed
+	pipeline.Grid = GridSquare
+	pipeline.Steps = []PipelineStep{}
+
+	if recipe.Grid != "" {
+		grid, err := ParseGridType(recipe.Grid)
+		if err != nil {
+			return err
+		}
+		pipeline.Grid = grid
+	}
+
+	for i, stepData := range recipe.Steps {
+
+		header := struct {
+			Type string `json:"type"`
+		}{}
+
+		if err := json.Unmarshal(stepData, &header); err != nil {
+			return fmt.Errorf("dngn: recipe step %d: %w", i, err)
+		}
+
+		decode, exists := pipelineSteps[header.Type]
+		if !exists {
+			return fmt.Errorf("dngn: recipe step %d has unknown type %q", i, header.Type)
+		}
+
+		step, err := decode(stepData)
+		if err != nil {
+			return fmt.Errorf("dngn: recipe step %d: %w", i, err)
+		}
+
+		pipeline.Steps = append(pipeline.Steps, step)
+
+	}
+
+	return nil
+
+}
+
+// Rune is a rune that's saved in JSON recipes as a single-character string (like "x"), rather than as a number.
+type Rune rune
+
+// MarshalJSON encodes the Rune as a single-character string.
+func (r Rune) MarshalJSON() ([]byte, error) {
+	return json.Marshal(string(rune(r)))
+}
+
+// UnmarshalJSON decodes the Rune from a single-character string. Numbers are accepted as well, and are used as the rune's value.
+func (r *Rune) UnmarshalJSON(data []byte) error {
+
+	str := ""
+
+	if err := json.Unmarshal(data, &str); err != nil {
+		value := int32(0)
+		if numErr := json.Unmarshal(data, &value); numErr != nil {
+			return fmt.Errorf("dngn: rune must be a single-character string, got %s", data)
+		}
+		*r = Rune(value)
+		return nil
+	}
+
+	runes := []rune(str)
+
+	if len(runes) != 1 {
+		return fmt.Errorf("dngn: rune must be a single-character string, got %q", str)
+	}
+
+	*r = Rune(runes[0])
+
+	return nil
+
+}
+
+// GenerateStep runs one of the Layout's Generate functions. Generator is the name of the function to run ("bsp", "rooms", "drunk",
+// "cyclic", "dla", "town", "chunks", or "cellular"); the other fields are the arguments for that function. Fields that don't apply to
+// the chosen generator are ignored, and BSP, drunk walk, cyclic, DLA, town, chunk, and cellular options that are left out use the
+// values from NewDefaultBSPOptions(), NewDefaultDrunkWalkOptions(), NewDefaultCyclicOptions(), NewDefaultDLAOptions(),
+// NewDefaultTownOptions(), NewDefaultChunkOptions(), and NewDefaultCellularOptions(). Options that can't be 0 are left out by leaving
+// them at 0, while options that can be 0 (like LoopChance, where 0 turns loops off) are pointers, and are left out by leaving them
+// nil. If Floor or Wall are 0, they default to ' ' and 'x'.
+type GenerateStep struct {
+	Generator string `json:"generator"`
+
+	Floor Rune `json:"floor,omitempty"` // Floor rune for "rooms", "drunk", "dla", "town", "chunks", and "cellular".
+	Wall  Rune `json:"wall,omitempty"`  // Wall rune for every generator.
+	Door  Rune `json:"door,omitempty"`  // Door rune for "bsp", "cyclic", and "town".
+
+	SplitCount      int  `json:"splitCount,omitempty"`      // See BSPOptions.SplitCount.
+	MinimumRoomSize int  `json:"minimumRoomSize,omitempty"` // See BSPOptions, CyclicOptions, and TownOptions.MinimumRoomSize.
+	CarveRooms      bool `json:"carveRooms,omitempty"`      // See BSPOptions.CarveRooms.
+	RoomPadding     *int `json:"roomPadding,omitempty"`     // See BSPOptions.RoomPadding.
+
+	DoorsPerWall    int      `json:"doorsPerWall,omitempty"`    // See BSPDoorPolicy.DoorsPerWall.
+	DoorWidth       int      `json:"doorWidth,omitempty"`       // See BSPDoorPolicy.DoorWidth.
+	LoopChance      *float32 `json:"loopChance,omitempty"`      // See BSPDoorPolicy.LoopChance.
+	DoorPlacement   string   `json:"doorPlacement,omitempty"`   // "random", "centered", or "corners"; see BSPDoorPolicy.Placement.
+	SkipBorderRooms bool     `json:"skipBorderRooms,omitempty"` // See BSPDoorPolicy.SkipBorderRooms.
+
+	AllowDisconnected bool `json:"allowDisconnected,omitempty"` // If true, BSPOptions.EnsureConnected is turned off.
+
+	RoomCount     int  `json:"roomCount,omitempty"` // See Layout.GenerateRandomRooms().
+	RoomMinWidth  int  `json:"roomMinWidth,omitempty"`
+	RoomMinHeight int  `json:"roomMinHeight,omitempty"`
+	RoomMaxWidth  int  `json:"roomMaxWidth,omitempty"`
+	RoomMaxHeight int  `json:"roomMaxHeight,omitempty"`
+	ConnectRooms  bool `json:"connectRooms,omitempty"`
+
+	PercentageFilled *float32 `json:"percentageFilled,omitempty"` // See Layout.GenerateDrunkWalk() and DLAOptions.PercentageFilled.
+
+	Walkers          int     `json:"walkers,omitempty"`          // See DrunkWalkOptions.Walkers.
+	MaxWalkers       int     `json:"maxWalkers,omitempty"`       // See DrunkWalkOptions.MaxWalkers.
+	Momentum         float32 `json:"momentum,omitempty"`         // See DrunkWalkOptions.Momentum.
+	BiasX            float32 `json:"biasX,omitempty"`            // See DrunkWalkOptions.BiasX.
+	BiasY            float32 `json:"biasY,omitempty"`            // See DrunkWalkOptions.BiasY.
+	SpawnChance      float32 `json:"spawnChance,omitempty"`      // See DrunkWalkOptions.SpawnChance.
+	DeathChance      float32 `json:"deathChance,omitempty"`      // See DrunkWalkOptions.DeathChance.
+	MaxSteps         int     `json:"maxSteps,omitempty"`         // See DrunkWalkOptions.MaxSteps.
+	RestartFromFloor bool    `json:"restartFromFloor,omitempty"` // See DrunkWalkOptions.RestartFromFloor.
+	BrushSize        int     `json:"brushSize,omitempty"`        // See DrunkWalkOptions.BrushSize.
+	EdgePadding      *int    `json:"edgePadding,omitempty"`      // See DrunkWalkOptions.Padding and DLAOptions.Padding.
+
+	Valve     Rune `json:"valve,omitempty"`     // See CyclicOptions.ValveValue.
+	CellSize  int  `json:"cellSize,omitempty"`  // See CyclicOptions.CellSize.
+	SubCycles *int `json:"subCycles,omitempty"` // See CyclicOptions.SubCycles.
+	Shortcuts *int `json:"shortcuts,omitempty"` // See CyclicOptions.Shortcuts.
+	Valves    *int `json:"valves,omitempty"`    // See CyclicOptions.Valves.
+
+	Spawn      string  `json:"spawn,omitempty"`      // "edges" or "anywhere"; see DLAOptions.Spawn.
+	Attraction float32 `json:"attraction,omitempty"` // See DLAOptions.Attraction.
+	MirrorX    bool    `json:"mirrorX,omitempty"`    // See DLAOptions.MirrorX.
+	MirrorY    bool    `json:"mirrorY,omitempty"`    // See DLAOptions.MirrorY.
+
+	StreetWidth    int      `json:"streetWidth,omitempty"`    // See TownOptions.StreetWidth.
+	BlockSize      int      `json:"blockSize,omitempty"`      // See TownOptions.BlockSize.
+	LotSize        int      `json:"lotSize,omitempty"`        // See TownOptions.LotSize.
+	BuildingChance *float32 `json:"buildingChance,omitempty"` // See TownOptions.BuildingChance.
+	NoPlaza        bool     `json:"noPlaza,omitempty"`        // If true, TownOptions.Plaza is turned off.
+
+	ChunkTemplates []ChunkTemplateEntry `json:"chunkTemplates,omitempty"` // See ChunkOptions.Templates; if empty, NewDefaultChunkTemplates() is used.
+	RandomChance   *float32             `json:"randomChance,omitempty"`   // See ChunkOptions.RandomChance.
+	DropChance     *float32             `json:"dropChance,omitempty"`     // See ChunkOptions.DropChance.
+	NoMirror       bool                 `json:"noMirror,omitempty"`       // If true, ChunkOptions.Mirror is turned off.
+	Border         *int                 `json:"border,omitempty"`         // See ChunkOptions.Border.
+
+	WallChance    *float32 `json:"wallChance,omitempty"`    // See CellularOptions.WallChance.
+	Iterations    *int     `json:"iterations,omitempty"`    // See CellularOptions.Iterations.
+	BirthLimit    int      `json:"birthLimit,omitempty"`    // See CellularOptions.BirthLimit.
+	SurvivalLimit int      `json:"survivalLimit,omitempty"` // See CellularOptions.SurvivalLimit.
+}
+
+// ChunkTemplateEntry describes a ChunkTemplate, so that it can be saved in a recipe. Openings are the template's open sides as
+// letters (any of "L", "R", "T", and "B", like "LRB"), or empty for a closed template.
+type ChunkTemplateEntry struct {
+	Openings string   `json:"openings,omitempty"`
+	Rows     []string `json:"rows"`
+}
+
+// template returns the ChunkTemplate the entry describes.
+func (entry ChunkTemplateEntry) template() (ChunkTemplate, error) {
+
+	template := ChunkTemplate{Rows: entry.Rows}
+
+	for _, letter := range entry.Openings {
+		switch letter {
+		case 'L':
+			template.Openings |= ChunkLeft
+		case 'R':
+			template.Openings |= ChunkRight
+		case 'T':
+			template.Openings |= ChunkTop
+		case 'B':
+			template.Openings |= ChunkBottom
+		default:
+			return template, fmt.Errorf("unknown chunk opening %q", letter)
+		}
+	}
+
+	return template, nil
+
+}
+
+// Apply runs the generator on the Layout.
+func (step GenerateStep) Apply(layout *Layout) error {
+
+	if step.Floor == 0 {
+		step.Floor = ' '
+	}
+
+	if step.Wall == 0 {
+		step.Wall = 'x'
+	}
+
+	switch step.Generator {
+
+	case "bsp":
+
+		options := NewDefaultBSPOptions()
+		options.WallValue = rune(step.Wall)
+		if step.Door != 0 {
+			options.DoorValue = rune(step.Door)
+		}
+		if step.SplitCount != 0 {
+			options.SplitCount = step.SplitCount
+		}
+		if step.MinimumRoomSize != 0 {
+			options.MinimumRoomSize = step.MinimumRoomSize
+		}
+		if step.RoomPadding != nil {
+			options.RoomPadding = *step.RoomPadding
+		}
+		options.CarveRooms = step.CarveRooms
+		if step.DoorsPerWall != 0 {
+			options.Doors.DoorsPerWall = step.DoorsPerWall
+		}
+		if step.DoorWidth != 0 {
+			options.Doors.DoorWidth = step.DoorWidth
+		}
+		if step.LoopChance != nil {
+			options.Doors.LoopChance = *step.LoopChance
+		}
+		options.Doors.SkipBorderRooms = step.SkipBorderRooms
+		options.EnsureConnected = !step.AllowDisconnected
+
+		switch step.DoorPlacement {
+		case "", "random":
+			options.Doors.Placement = DoorPlacementRandom
+		case "centered":
+			options.Doors.Placement = DoorPlacementCentered
+		case "corners":
+			options.Doors.Placement = DoorPlacementCorners
+		default:
+			return fmt.Errorf("unknown door placement %q", step.DoorPlacement)
+		}
+
+		_, err := layout.GenerateBSP(options)
+		return err
+
+	case "rooms":
+
+		_, err := layout.GenerateRandomRooms(rune(step.Floor), rune(step.Wall), step.RoomCount, step.RoomMinWidth, step.RoomMinHeight, step.RoomMaxWidth, step.RoomMaxHeight, step.ConnectRooms)
+		return err
+
+	case "drunk":
+
+		options := NewDefaultDrunkWalkOptions()
+		options.FloorValue = rune(step.Floor)
+		options.WallValue = rune(step.Wall)
+		if step.PercentageFilled != nil {
+			options.PercentageFilled = *step.PercentageFilled
+		}
+		if step.Walkers != 0 {
+			options.Walkers = step.Walkers
+		}
+		if step.BrushSize != 0 {
+			options.BrushSize = step.BrushSize
+		}
+		options.MaxWalkers = step.MaxWalkers
+		options.Momentum = step.Momentum
+		options.BiasX = step.BiasX
+		options.BiasY = step.BiasY
+		options.SpawnChance = step.SpawnChance
+		options.DeathChance = step.DeathChance
+		options.MaxSteps = step.MaxSteps
+		options.RestartFromFloor = step.RestartFromFloor
+		if step.EdgePadding != nil {
+			options.Padding = *step.EdgePadding
+		}
+
+		_, err := layout.GenerateDrunkWalkWithOptions(options)
+		return err
+
+	case "cyclic":
+
+		options := NewDefaultCyclicOptions()
+		options.WallValue = rune(step.Wall)
+		if step.Door != 0 {
+			options.DoorValue = rune(step.Door)
+		}
+		if step.Valve != 0 {
+			options.ValveValue = rune(step.Valve)
+		}
+		if step.MinimumRoomSize != 0 {
+			options.MinimumRoomSize = step.MinimumRoomSize
+		}
+		if step.CellSize != 0 {
+			options.CellSize = step.CellSize
+		}
+		if step.SubCycles != nil {
+			options.SubCycles = *step.SubCycles
+		}
+		if step.Shortcuts != nil {
+			options.Shortcuts = *step.Shortcuts
+		}
+		if step.Valves != nil {
+			options.Valves = *step.Valves
+		}
+
+		_, err := layout.GenerateCyclic(options)
+		return err
+
+	case "dla":
+
+		options := NewDefaultDLAOptions()
+		options.FloorValue = rune(step.Floor)
+		options.WallValue = rune(step.Wall)
+		if step.PercentageFilled != nil {
+			options.PercentageFilled = *step.PercentageFilled
+		}
+		if step.EdgePadding != nil {
+			options.Padding = *step.EdgePadding
+		}
+		options.Attraction = step.Attraction
+		options.MirrorX = step.MirrorX
+		options.MirrorY = step.MirrorY
+
+		switch step.Spawn {
+		case "", "edges":
+			options.Spawn = DLASpawnEdges
+		case "anywhere":
+			options.Spawn = DLASpawnAnywhere
+		default:
+			return fmt.Errorf("unknown particle spawn %q", step.Spawn)
+		}
+
+		_, err := layout.GenerateDLA(options)
+		return err
+
+	case "town":
+
+		options := NewDefaultTownOptions()
+		options.FloorValue = rune(step.Floor)
+		options.WallValue = rune(step.Wall)
+		if step.Door != 0 {
+			options.DoorValue = rune(step.Door)
+		}
+		if step.MinimumRoomSize != 0 {
+			options.MinimumRoomSize = step.MinimumRoomSize
+		}
+		if step.StreetWidth != 0 {
+			options.StreetWidth = step.StreetWidth
+		}
+		if step.BlockSize != 0 {
+			options.BlockSize = step.BlockSize
+		}
+		if step.LotSize != 0 {
+			options.LotSize = step.LotSize
+		}
+		if step.BuildingChance != nil {
+			options.BuildingChance = *step.BuildingChance
+		}
+		options.Plaza = !step.NoPlaza
+
+		_, err := layout.GenerateTown(options)
+		return err
+
+	case "chunks":
+
+		options := NewDefaultChunkOptions()
+		options.FloorValue = rune(step.Floor)
+		options.WallValue = rune(step.Wall)
+		if len(step.ChunkTemplates) > 0 {
+			options.Templates = []ChunkTemplate{}
+			for _, entry := range step.ChunkTemplates {
+				template, err := entry.template()
+				if err != nil {
+					return err
+				}
+				options.Templates = append(options.Templates, template)
+			}
+		}
+		if step.RandomChance != nil {
+			options.RandomChance = *step.RandomChance
+		}
+		if step.DropChance != nil {
+			options.DropChance = *step.DropChance
+		}
+		if step.Border != nil {
+			options.Border = *step.Border
+		}
+		options.Mirror = !step.NoMirror
+
+		_, err := layout.GenerateChunks(options)
+		return err
+
+	case "cellular":
+
+		options := NewDefaultCellularOptions()
+		options.FloorValue = rune(step.Floor)
+		options.WallValue = rune(step.Wall)
+		if step.WallChance != nil {
+			options.WallChance = *step.WallChance
+		}
+		if step.Iterations != nil {
+			options.Iterations = *step.Iterations
+		}
+		options.BirthLimit = step.BirthLimit
+		options.SurvivalLimit = step.SurvivalLimit
+
+		_, err := layout.GenerateCellular(options)
+		return err
+
+	}
+
+	return fmt.Errorf("unknown generator %q", step.Generator)
+
+}
+
+// SelectStep selects every cell in the Layout, runs the selection through each of the Filters in order, and then fills the cells
+// left in the Selection with the Fill rune.
+type SelectStep struct {
+	Filters []SelectFilter `json:"filters"`
+	Fill    Rune           `json:"fill"`
+}
+
+// Apply filters the Selection and fills it.
+func (step SelectStep) Apply(layout *Layout) error {
+
+	selection := layout.Select()
+
+	for _, filter := range step.Filters {
+		var err error
+		if selection, err = filter.Apply(selection); err != nil {
+			return err
+		}
+	}
+
+	selection.Fill(rune(step.Fill))
+
+	return nil
+
+}
+
+// SelectFilter describes one of the Selection filtering functions, so that it can be saved in a recipe. Type is the kind of filter:
+//
+// "rune" - Selection.FilterByRune(Rune).
+// "percentage" - Selection.FilterByPercentage(Percentage).
+// "area" - Selection.FilterByArea(X, Y, W, H).
+// "neighbor" - Selection.FilterByNeighbor(Rune, Count, Diagonal, AtMost).
+// "expand" - Selection.Expand(Distance, Diagonal).
+// "invert" - Selection.Invert().
+// "poisson" - Selection.FilterByPoissonDisc(Distance).
+// "border" - Only the cells within Distance cells of the Layout's edges (the outer walls, for example).
+type SelectFilter struct {
+	Type       string  `json:"type"`
+	Rune       Rune    `json:"rune,omitempty"`
+	Percentage float32 `json:"percentage,omitempty"`
+	X          int     `json:"x,omitempty"`
+	Y          int     `json:"y,omitempty"`
+	W          int     `json:"w,omitempty"`
+	H          int     `json:"h,omitempty"`
+	Count      int     `json:"count,omitempty"`
+	Distance   int     `json:"distance,omitempty"`
+	Diagonal   bool    `json:"diagonal,omitempty"`
+	AtMost     bool    `json:"atMost,omitempty"`
+}
+
+// Apply returns the Selection provided, filtered.
+func (filter SelectFilter) Apply(selection Selection) (Selection, error) {
+
+	switch filter.Type {
+	case "rune":
+		return selection.FilterByRune(rune(filter.Rune)), nil
+	case "percentage":
+		return selection.FilterByPercentage(filter.Percentage), nil
+	case "area":
+		return selection.FilterByArea(filter.X, filter.Y, filter.W, filter.H), nil
+	case "neighbor":
+		return selection.FilterByNeighbor(rune(filter.Rune), filter.Count, filter.Diagonal, filter.AtMost), nil
+	case "expand":
+		return selection.Expand(filter.Distance, filter.Diagonal), nil
+	case "invert":
+		return selection.Invert(), nil
+	case "poisson":
+		return selection.FilterByPoissonDisc(float64(filter.Distance)), nil
+	case "border":
+		layout := selection.Layout
+		return selection.FilterBy(func(x, y int) bool {
+			return x < filter.Distance || y < filter.Distance || x >= layout.Width-filter.Distance || y >= layout.Height-filter.Distance
+		}), nil
+	}
+
+	return selection, fmt.Errorf("unknown selection filter %q", filter.Type)
+
+}
+
+// ConnectStep connects every separate area of Floor cells together using Layout.ConnectRegions(), carving paths with Fill (or
+// Floor, if Fill is 0).
+type ConnectStep struct {
+	Floor Rune `json:"floor"`
+	Fill  Rune `json:"fill,omitempty"`
+}
+
+// Apply connects the regions.
+func (step ConnectStep) Apply(layout *Layout) error {
+
+	fill := step.Fill
+	if fill == 0 {
+		fill = step.Floor
+	}
+
+	layout.ConnectRegions(rune(step.Floor), rune(fill))
+
+	return nil
+
+}
+
+// PrefabStep places copies of a hand-made piece of map (the Prefab, one string per row) at random positions in the Layout. A
+// prefab can only be placed where every cell it covers has the On rune. Cells in the prefab with the Transparent rune aren't
+// copied, leaving the Layout's cell as-is. If Rotate is true, each copy is rotated randomly before being placed.
+// Count is how many copies to place; if there's no space left for a copy, the step stops placing them (a ValidateStep can be
+// used to check that the prefab actually made it into the Layout).
+type PrefabStep struct {
+	Prefab      []string `json:"prefab"`
+	Count       int      `json:"count"`
+	On          Rune     `json:"on"`
+	Transparent Rune     `json:"transparent,omitempty"`
+	Rotate      bool     `json:"rotate,omitempty"`
+}
+
+// Apply places the prefabs.
+func (step PrefabStep) Apply(layout *Layout) error {
+
+	for i := 0; i < step.Count; i++ {
+
+		prefab, err := NewLayoutFromStringArray(step.Prefab)
+		if err != nil {
+			return err
+		}
+
+		if step.Rotate {
+			for r := layout.RNG.Intn(4); r > 0; r-- {
+				prefab.Rotate()
+			}
+		}
+
+		spots := []Position{}
+
+		for y := 0; y <= layout.Height-prefab.Height; y++ {
+			for x := 0; x <= layout.Width-prefab.Width; x++ {
+				if layout.areaIs(x, y, prefab.Width, prefab.Height, rune(step.On)) {
+					spots = append(spots, Position{x, y})
+				}
+			}
+		}
+
+		if len(spots) == 0 {
+			break
+		}
+
+		spot := spots[layout.RNG.Intn(len(spots))]
+
+		for y := 0; y < prefab.Height; y++ {
+			for x := 0; x < prefab.Width; x++ {
+				if value := prefab.Get(x, y); value != rune(step.Transparent) || step.Transparent == 0 {
+					layout.Set(spot.X+x, spot.Y+y, value)
+				}
+			}
+		}
+
+	}
+
+	return nil
+
+}
+
+// NoiseStep fills the Layout from one or more noise fields using Layout.GenerateFromNoise(). Each field is seeded from the
+// Layout's RNG, so the terrain changes with the Pipeline's seed. If no Bands are given, the bands from NewDefaultNoiseBands()
+// are used.
+type NoiseStep struct {
+	Fields []NoiseField     `json:"fields"`
+	Bands  []NoiseBandEntry `json:"bands,omitempty"`
+}
+
+// NoiseField describes a Noise, so that it can be saved in a recipe. Type is "value", "perlin", "simplex", or "opensimplex"
+// (simplex, if empty); the other fields match the Noise's fields, and use the values from NewNoise() if they're 0.
+type NoiseField struct {
+	Type          string  `json:"type,omitempty"`
+	Frequency     float64 `json:"frequency,omitempty"`
+	Octaves       int     `json:"octaves,omitempty"`
+	Lacunarity    float64 `json:"lacunarity,omitempty"`
+	Gain          float64 `json:"gain,omitempty"`
+	Ridged        bool    `json:"ridged,omitempty"`
+	WarpStrength  float64 `json:"warpStrength,omitempty"`
+	WarpFrequency float64 `json:"warpFrequency,omitempty"`
+}
+
+// noise returns a new Noise with the field's settings, seeded from the Layout's RNG.
+func (field NoiseField) noise(layout *Layout) (*Noise, error) {
+
+	noiseType := NoiseSimplex
+
+	switch field.Type {
+	case "", "simplex":
+	case "value":
+		noiseType = NoiseValue
+	case "perlin":
+		noiseType = NoisePerlin
+	case "opensimplex":
+		noiseType = NoiseOpenSimplex
+	default:
+		return nil, fmt.Errorf("unknown noise type %q", field.Type)
+	}
+
+	noise := NewNoise(noiseType, layout.RNG.Int63())
+	if field.Frequency != 0 {
+		noise.Frequency = field.Frequency
+	}
+	if field.Octaves != 0 {
+		noise.Octaves = field.Octaves
+	}
+	if field.Lacunarity != 0 {
+		noise.Lacunarity = field.Lacunarity
+	}
+	if field.Gain != 0 {
+		noise.Gain = field.Gain
+	}
+	noise.Ridged = field.Ridged
+	noise.WarpStrength = field.WarpStrength
+	noise.WarpFrequency = field.WarpFrequency
+
+	return noise, nil
+
+}
+
+// NoiseBandEntry describes a NoiseBand, so that it can be saved in a recipe.
+type NoiseBandEntry struct {
+	Value Rune      `json:"value"`
+	Max   []float64 `json:"max"`
+}
+
+// Apply fills the Layout from the noise fields.
+func (step NoiseStep) Apply(layout *Layout) error {
+
+	fields := []*Noise{}
+
+	for _, field := range step.Fields {
+		noise, err := field.noise(layout)
+		if err != nil {
+			return err
+		}
+		fields = append(fields, noise)
+	}
+
+	bands := NewDefaultNoiseBands()
+
+	if len(step.Bands) > 0 {
+		bands = []NoiseBand{}
+		for _, band := range step.Bands {
+			bands = append(bands, NoiseBand{Value: rune(band.Value), Max: band.Max})
+		}
+	}
+
+	_, err := layout.GenerateFromNoise(fields, bands)
+	return err
+
+}
+
+// BiomeStep fills the Layout with biomes using Layout.GenerateBiomes(). Each field is seeded from the Layout's RNG. Temperature
+// and Moisture are optional (see BiomeFields), and if no Biomes are given, the biomes from NewDefaultBiomes() are used.
+type BiomeStep struct {
+	Elevation   NoiseField   `json:"elevation"`
+	Temperature *NoiseField  `json:"temperature,omitempty"`
+	Moisture    *NoiseField  `json:"moisture,omitempty"`
+	Biomes      []BiomeEntry `json:"biomes,omitempty"`
+}
+
+// BiomeEntry describes a Biome, so that it can be saved in a recipe.
+type BiomeEntry struct {
+	Name           string  `json:"name"`
+	Value          Rune    `json:"value"`
+	MaxElevation   float64 `json:"maxElevation"`
+	MaxTemperature float64 `json:"maxTemperature"`
+	MaxMoisture    float64 `json:"maxMoisture"`
+}
+
+// Apply fills the Layout with biomes.
+func (step BiomeStep) Apply(layout *Layout) error {
+
+	fields := BiomeFields{}
+
+	var err error
+
+	if fields.Elevation, err = step.Elevation.noise(layout); err != nil {
+		return err
+	}
+
+	if step.Temperature != nil {
+		if fields.Temperature, err = step.Temperature.noise(layout); err != nil {
+			return err
+		}
+	}
+
+	if step.Moisture != nil {
+		if fields.Moisture, err = step.Moisture.noise(layout); err != nil {
+			return err
+		}
+	}
+
+	biomes := NewDefaultBiomes()
+
+	if len(step.Biomes) > 0 {
+		biomes = []Biome{}
+		for _, biome := range step.Biomes {
+			biomes = append(biomes, Biome{
+				Name:           biome.Name,
+				Value:          rune(biome.Value),
+				MaxElevation:   biome.MaxElevation,
+				MaxTemperature: biome.MaxTemperature,
+				MaxMoisture:    biome.MaxMoisture,
+			})
+		}
+	}
+
+	_, err = layout.GenerateBiomes(fields, biomes)
+	return err
+
+}
+
+// ValidateStep checks the Layout, returning an error wrapping ErrValidation if it doesn't meet the requirements. Connected requires
+// that all Floor cells are reachable from each other (moving in cardinal directions). MinFloor and MaxFloor are the minimum and
+// maximum percentage (0 - 1) of the Layout that can be Floor cells (a value of 0 disables the check). Require is a list of runes
+// that must appear in the Layout at least once.
+type ValidateStep struct {
+	Floor     Rune    `json:"floor"`
+	Connected bool    `json:"connected,omitempty"`
+	MinFloor  float32 `json:"minFloor,omitempty"`
+	MaxFloor  float32 `json:"maxFloor,omitempty"`
+	Require   []Rune  `json:"require,omitempty"`
+}
+
+// Apply validates the Layout.
+func (step ValidateStep) Apply(layout *Layout) error {
+
+	problems := []string{}
+
+	floor := layout.Select().FilterByRune(rune(step.Floor))
+	floorPercentage := float32(len(floor.Cells)) / float32(layout.Area())
+
+	if step.Connected && len(layout.Regions(rune(step.Floor), false)) > 1 {
+		problems = append(problems, "floor isn't connected")
+	}
+
+	if step.MinFloor > 0 && floorPercentage < step.MinFloor {
+		problems = append(problems, fmt.Sprintf("floor covers %.2f of the layout, less than %.2f", floorPercentage, step.MinFloor))
+	}
+
+	if step.MaxFloor > 0 && floorPercentage > step.MaxFloor {
+		problems = append(problems, fmt.Sprintf("floor covers %.2f of the layout, more than %.2f", floorPercentage, step.MaxFloor))
+	}
+
+	for _, required := range step.Require {
+		if len(layout.Select().FilterByRune(rune(required)).Cells) == 0 {
+			problems = append(problems, fmt.Sprintf("layout has no %q cells", rune(required)))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrValidation, strings.Join(problems, ", "))
+	}
+
+	return nil
+
+}
+
+// FuncStep is a PipelineStep that runs a Go function, for steps that can't be described by the other PipelineSteps. FuncSteps
+// can't be saved in JSON recipes.
+type FuncStep func(layout *Layout) error
+
+// Apply runs the function.
+func (step FuncStep) Apply(layout *Layout) error {
+	return step(layout)
+}
+
+// areaIs returns if every cell in the given area of the Layout has the value provided.
+func (layout *Layout) areaIs(x, y, w, h int, value rune) bool {
+	for cy := y; cy < y+h; cy++ {
+		for cx := x; cx < x+w; cx++ {
+			if layout.Get(cx, cy) != value {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,95 @@
+package dngn
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const testJSONRecipe = `{
+	"width": 40,
+	"height": 30,
+	"seed": 7,
+	"steps": [
+		{ "type": "generate", "generator": "bsp", "loopChance": 0 },
+		{ "type": "select", "filters": [ { "type": "border", "distance": 1 } ], "fill": "x" }
+	]
+}`
+
+const testYAMLRecipe = `
+width: 40
+height: 30
+seed: 7
+steps:
+  - type: generate
+    generator: bsp
+    loopChance: 0
+  - { type: select, filters: [ { type: border, distance: 1 } ], fill: "x" }
+`
+
+// TestPipelineRecipes checks that JSON and YAML recipes describe the same Pipeline, that an explicit 0 in a recipe is kept rather
+// than being replaced by the default, and that running a Pipeline again gives the same Layout.
+func TestPipelineRecipes(t *testing.T) {
+
+	fromJSON, err := ReadPipeline(strings.NewReader(testJSONRecipe))
+	if err != nil {
+		t.Fatalf("JSON: %v", err)
+	}
+
+	fromYAML, err := ReadPipeline(strings.NewReader(testYAMLRecipe))
+	if err != nil {
+		t.Fatalf("YAML: %v", err)
+	}
+
+	jsonData, err := json.Marshal(fromJSON)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	yamlData, err := json.Marshal(fromYAML)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if string(jsonData) != string(yamlData) {
+		t.Errorf("JSON and YAML recipes differ:\n%s\n%s", jsonData, yamlData)
+	}
+
+	step := fromYAML.Steps[0].(GenerateStep)
+	if step.LoopChance == nil || *step.LoopChance != 0 {
+		t.Errorf("loopChance of 0 wasn't kept, got %v", step.LoopChance)
+	}
+
+	if !strings.Contains(string(jsonData), `"loopChance":0`) {
+		t.Errorf("loopChance of 0 wasn't saved: %s", jsonData)
+	}
+
+	first, err := fromJSON.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := fromYAML.Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if first.DataToString() != second.DataToString() {
+		t.Errorf("the same recipe generated different layouts:\n%s\n%s", first.DataToString(), second.DataToString())
+	}
+
+}
+
+// TestPipelineDefaults checks that options left out of a GenerateStep use the generator's defaults.
+func TestPipelineDefaults(t *testing.T) {
+
+	layout, err := NewPipeline(40, 30, 1, GenerateStep{Generator: "drunk"}).Generate()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if filled := float32(len(layout.Select().FilterByRune(' ').Cells)) / float32(layout.Area()); filled < NewDefaultDrunkWalkOptions().PercentageFilled {
+		t.Errorf("drunk walk with the default percentage filled only filled %v of the layout", filled)
+	}
+
+}
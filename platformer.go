@@ -0,0 +1,332 @@
+package dngn
+
+import (
+	"fmt"
+)
+
+// PlatformerOptions describes how the player moves in a side-view platformer, for Layout.SelectStandable(), Layout.SelectReachable(),
+// and Layout.ConnectPlatforms(). Cells outside of the Layout count as solid, so the player can stand on the bottom edge of the
+// Layout, but can't leave it.
+type PlatformerOptions struct {
+	SolidValues   []rune // The runes the player can stand on, but can't move through (like walls).
+	LadderValue   rune   // The rune for ladders, which the player can climb up and down, and stand on anywhere along.
+	PlatformValue rune   // The rune for the platforms placed by Layout.ConnectPlatforms(); platforms are solid.
+
+	JumpHeight int // How many cells the player can jump up.
+	JumpWidth  int // How many cells the player can move sideways at the top of a jump; at least 1, since walking is a jump with no height.
+	MaxFall    int // The most cells the player can fall without getting hurt; if 0, falls of any height are safe.
+}
+
+// NewDefaultPlatformerOptions returns the default PlatformerOptions: solid walls ('x'), ladders ('H'), and platforms ('='), for a
+// player that can jump 3 cells up and 3 cells across, and fall any distance.
+func NewDefaultPlatformerOptions() PlatformerOptions {
+	return PlatformerOptions{
+		SolidValues:   []rune{'x'},
+		LadderValue:   'H',
+		PlatformValue: '=',
+		JumpHeight:    3,
+		JumpWidth:     3,
+	}
+}
+
+// validate returns an error if the options are invalid; function is the name of the function they were passed to.
+func (options PlatformerOptions) validate(function string) error {
+
+	if options.JumpHeight < 0 {
+		return invalidArgument(function, "jump height can't be negative, got %d", options.JumpHeight)
+	}
+
+	if options.JumpWidth < 1 {
+		return invalidArgument(function, "jump width must be at least 1, got %d", options.JumpWidth)
+	}
+
+	if options.MaxFall < 0 {
+		return invalidArgument(function, "max fall can't be negative, got %d", options.MaxFall)
+	}
+
+	for _, value := range options.SolidValues {
+		if value == options.LadderValue {
+			return invalidArgument(function, "the ladder rune %q can't be solid", value)
+		}
+	}
+
+	if options.LadderValue == options.PlatformValue {
+		return invalidArgument(function, "the ladder and platform runes can't both be %q", options.LadderValue)
+	}
+
+	return nil
+
+}
+
+// platformer answers questions about how the player can move around a Layout.
+type platformer struct {
+	layout  *Layout
+	options PlatformerOptions
+}
+
+// solid returns if the cell at the position provided is solid; cells outside of the Layout are.
+func (p platformer) solid(x, y int) bool {
+
+	if x < 0 || y < 0 || x >= p.layout.Width || y >= p.layout.Height {
+		return true
+	}
+
+	value := p.layout.Get(x, y)
+
+	if value == p.options.PlatformValue {
+		return true
+	}
+
+	for _, solid := range p.options.SolidValues {
+		if value == solid {
+			return true
+		}
+	}
+
+	return false
+
+}
+
+// ladder returns if the cell at the position provided is a ladder.
+func (p platformer) ladder(x, y int) bool {
+	return !p.solid(x, y) && p.layout.Get(x, y) == p.options.LadderValue
+}
+
+// standable returns if the player can stand in the cell at the position provided, which they can if it's open and either has something
+// solid underneath it, or is on or just above a ladder.
+func (p platformer) standable(x, y int) bool {
+	return !p.solid(x, y) && (p.solid(x, y+1) || p.ladder(x, y) || p.ladder(x, y+1))
+}
+
+// land returns the cell the player lands in after falling from the position provided, and if they can land there safely. A player
+// that's already standable doesn't fall at all.
+func (p platformer) land(x, y int) (Position, bool) {
+
+	for fy := y; !p.solid(x, fy); fy++ {
+
+		if p.options.MaxFall > 0 && fy-y > p.options.MaxFall {
+			break
+		}
+
+		if p.standable(x, fy) {
+			return Position{x, fy}, true
+		}
+
+	}
+
+	return Position{}, false
+
+}
+
+// moves returns every standable cell the player can get to from the standable cell provided in a single move: climbing up or down
+// a ladder, or jumping (or walking off of a ledge) and landing.
+func (p platformer) moves(x, y int) []Position {
+
+	moves := []Position{}
+
+	if p.ladder(x, y) && p.standable(x, y-1) {
+		moves = append(moves, Position{x, y - 1})
+	}
+
+	if p.ladder(x, y+1) {
+		moves = append(moves, Position{x, y + 1})
+	}
+
+	// Jumps are simplified to a straight line up to the height of the jump, then a straight line across, and then a fall; if any
+	// cell along the way is solid, the jump is cut short there.
+	for h := 0; h <= p.options.JumpHeight && !p.solid(x, y-h); h++ {
+
+		for _, dir := range []int{-1, 1} {
+
+			for d := 0; d <= p.options.JumpWidth; d++ {
+
+				if d == 0 && (h == 0 || dir > 0) {
+					continue
+				}
+
+				if p.solid(x+dir*d, y-h) {
+					break
+				}
+
+				if landing, ok := p.land(x+dir*d, y-h); ok && landing != (Position{x, y}) {
+					moves = append(moves, landing)
+				}
+
+			}
+
+		}
+
+	}
+
+	return moves
+
+}
+
+// SelectStandable returns a Selection of every cell in the Layout that the player of a side-view platformer can stand in: open
+// (non-solid) cells with something solid underneath them, as well as ladders and the cells just above them.
+func (layout *Layout) SelectStandable(options PlatformerOptions) Selection {
+
+	p := platformer{layout, options}
+	selection := Selection{Layout: layout, Cells: map[Position]bool{}}
+
+	for y := 0; y < layout.Height; y++ {
+		for x := 0; x < layout.Width; x++ {
+			if p.standable(x, y) {
+				selection.Cells[Position{x, y}] = true
+			}
+		}
+	}
+
+	return selection
+
+}
+
+// SelectReachable returns a Selection of every standable cell (see SelectStandable()) the player of a side-view platformer can get to
+// from the position provided, by walking, jumping, falling, and climbing ladders as the options allow. If the starting position is
+// in the air, the player falls from it first; if it's solid, or the player can't land safely from it, the Selection is empty.
+func (layout *Layout) SelectReachable(x, y int, options PlatformerOptions) Selection {
+
+	p := platformer{layout, options}
+	selection := Selection{Layout: layout, Cells: map[Position]bool{}}
+
+	if p.solid(x, y) {
+		return selection
+	}
+
+	start, ok := p.land(x, y)
+	if !ok {
+		return selection
+	}
+
+	toVisit := []Position{start}
+	selection.Cells[start] = true
+
+	for len(toVisit) > 0 {
+
+		cell := toVisit[0]
+		toVisit = toVisit[1:]
+
+		for _, next := range p.moves(cell.X, cell.Y) {
+			if !selection.Cells[next] {
+				selection.Cells[next] = true
+				toVisit = append(toVisit, next)
+			}
+		}
+
+	}
+
+	return selection
+
+}
+
+// ConnectPlatforms adds ladders and platforms to the Layout until the player of a side-view platformer can get from the start position
+// provided to the end position (to an exit, say). Each pass, it finds the shortest way through open cells to the end from any cell
+// the player can already reach (see SelectReachable()), then puts ladders along the parts of that way that go up or down, and
+// platforms under the parts that cross the air, replacing whatever open cells were there.
+// ConnectPlatforms returns a Selection of the cells it placed ladders or platforms in, or an error wrapping ErrNoPath if there's no
+// way through open cells to the end, or ErrInvalidArgument if the options are invalid or either position is solid.
+func (layout *Layout) ConnectPlatforms(start, end Position, options PlatformerOptions) (Selection, error) {
+
+	placed := Selection{Layout: layout, Cells: map[Position]bool{}}
+
+	if err := options.validate("ConnectPlatforms"); err != nil {
+		return placed, err
+	}
+
+	p := platformer{layout, options}
+
+	if p.solid(start.X, start.Y) || p.solid(end.X, end.Y) {
+		return placed, invalidArgument("ConnectPlatforms", "the start %v and end %v need to be open cells in the Layout", start, end)
+	}
+
+	directions := []Position{{0, -1}, {-1, 0}, {1, 0}, {0, 1}}
+
+	for {
+
+		reached := layout.SelectReachable(start.X, start.Y, options)
+
+		if reached.Contains(end.X, end.Y) {
+			return placed, nil
+		}
+
+		// Search outwards from every reached cell at once (going through them in order, so that the result doesn't depend on the
+		// map's iteration order) for the end.
+		from := map[Position]Position{}
+		toVisit := []Position{}
+
+		for y := 0; y < layout.Height; y++ {
+			for x := 0; x < layout.Width; x++ {
+				if reached.Contains(x, y) {
+					from[Position{x, y}] = Position{x, y}
+					toVisit = append(toVisit, Position{x, y})
+				}
+			}
+		}
+
+		if len(toVisit) == 0 {
+			// The player can't land anywhere from the start, so the way starts from the start itself.
+			from[start] = start
+			toVisit = append(toVisit, start)
+		}
+
+		for len(toVisit) > 0 && !hasPosition(from, end) {
+
+			cell := toVisit[0]
+			toVisit = toVisit[1:]
+
+			for _, dir := range directions {
+				next := Position{cell.X + dir.X, cell.Y + dir.Y}
+				if !hasPosition(from, next) && !p.solid(next.X, next.Y) {
+					from[next] = cell
+					toVisit = append(toVisit, next)
+				}
+			}
+
+		}
+
+		if !hasPosition(from, end) {
+			return placed, fmt.Errorf("dngn: ConnectPlatforms: can't get from %v to %v: %w", start, end, ErrNoPath)
+		}
+
+		path := []Position{end}
+		for cell := end; from[cell] != cell; cell = from[cell] {
+			path = append([]Position{from[cell]}, path...)
+		}
+
+		changed := false
+
+		// Going up or down takes a ladder. Since the way is as short as it can be, it never runs alongside itself, so the
+		// platforms placed under the rest of it below can't block it.
+		for i := 1; i < len(path); i++ {
+			if path[i].X == path[i-1].X {
+				for _, cell := range path[i-1 : i+1] {
+					if !p.ladder(cell.X, cell.Y) {
+						layout.Set(cell.X, cell.Y, options.LadderValue)
+						placed.Cells[cell] = true
+						changed = true
+					}
+				}
+			}
+		}
+
+		for _, cell := range path {
+			if !p.standable(cell.X, cell.Y) {
+				layout.Set(cell.X, cell.Y+1, options.PlatformValue)
+				placed.Cells[Position{cell.X, cell.Y + 1}] = true
+				changed = true
+			}
+		}
+
+		if !changed {
+			return placed, fmt.Errorf("dngn: ConnectPlatforms: can't get from %v to %v: %w", start, end, ErrCannotConverge)
+		}
+
+	}
+
+}
+
+// hasPosition returns if the position provided is a key in the map.
+func hasPosition(positions map[Position]Position, position Position) bool {
+	_, ok := positions[position]
+	return ok
+}
@@ -0,0 +1,62 @@
+package dngn
+
+import (
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+// TestConnectPlatforms checks that after connecting platforms through scattered walls, the player can get from the start to the
+// end, and that an end that's walled off gives ErrNoPath.
+func TestConnectPlatforms(t *testing.T) {
+
+	options := NewDefaultPlatformerOptions()
+
+	for seed := int64(1); seed <= 10; seed++ {
+
+		layout := NewLayout(40, 30)
+		layout.RNG = rand.New(rand.NewSource(seed))
+		layout.Select().Fill(' ')
+		layout.Select().FilterByPercentage(0.15).Fill('x')
+
+		start, end := Position{1, 28}, Position{38, 1}
+		layout.Set(start.X, start.Y, ' ')
+		layout.Set(end.X, end.Y, ' ')
+
+		// The scattered walls might wall the end off; this is checked before any ladders or platforms are placed.
+		open := layout.SelectContiguous(start.X, start.Y, false)
+		connected := open.Contains(end.X, end.Y)
+
+		_, err := layout.ConnectPlatforms(start, end, options)
+
+		if !connected {
+			if !errors.Is(err, ErrNoPath) {
+				t.Errorf("seed %d: expected ErrNoPath with the end walled off, got %v", seed, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Errorf("seed %d: %v", seed, err)
+			continue
+		}
+
+		if reached := layout.SelectReachable(start.X, start.Y, options); !reached.Contains(end.X, end.Y) {
+			t.Errorf("seed %d: the end can't be reached after connecting platforms", seed)
+		}
+
+	}
+
+	layout, _ := NewLayoutFromStringArray([]string{
+		"          ",
+		" xxx      ",
+		" x x      ",
+		" xxx      ",
+		"          ",
+	})
+
+	if _, err := layout.ConnectPlatforms(Position{6, 4}, Position{2, 2}, options); !errors.Is(err, ErrNoPath) {
+		t.Errorf("expected ErrNoPath into a closed box, got %v", err)
+	}
+
+}
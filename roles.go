@@ -0,0 +1,197 @@
+package dngn
+
+import (
+	"math"
+	"sort"
+)
+
+// The gameplay roles assigned to rooms by the RoleRules returned from NewDefaultRoleRules(). Roles are stored as tags on each room.
+const (
+	RoleStart    = "start"
+	RoleExit     = "exit"
+	RoleBoss     = "boss"
+	RoleTreasure = "treasure"
+	RoleSecret   = "secret"
+)
+
+// RoomInfo describes a room for the Filter and Score functions of a RoleRule.
+type RoomInfo struct {
+	Room               *Room
+	Area               int     // The room's area.
+	HopsFromStart      int     // Hops from the start room, or -1 if the start room hasn't been picked yet or can't reach this room.
+	MaxHopsFromStart   int     // The most hops it takes to reach any room from the start room (0 before the start room is picked).
+	DeadEnd            bool    // If the room is only connected to one other room.
+	Chokepoint         bool    // If removing the room would cut other rooms off from each other (see ArticulationRooms()).
+	DistanceFromCenter float64 // Distance from the room's center to the center of the area covered by all of the rooms.
+}
+
+// RoleRule describes how to pick the rooms that get a role (tag). Rooms that pass the Filter (or all rooms, if Filter is nil) are
+// sorted by Score, highest first (or left in order, if Score is nil), and the first Count rooms are tagged with Tag. If Count is 0
+// or less, every room that passes the Filter is tagged. Unless AllowTagged is true, rooms that already have a tag are skipped.
+type RoleRule struct {
+	Tag         string
+	Count       int
+	Filter      func(info RoomInfo) bool
+	Score       func(info RoomInfo) float64
+	AllowTagged bool
+}
+
+// RoleRules is the set of rules AssignRoles() uses to assign roles to rooms. The Start rule is applied first; once the start room is
+// picked, the rest of the Rules are applied in order, with each room's HopsFromStart available.
+type RoleRules struct {
+	Start RoleRule
+	Rules []RoleRule
+}
+
+// NewDefaultRoleRules returns RoleRules that assign the usual roles: the start room is the room nearest the center, the exit is
+// the room farthest from the start (in hops), the boss room is the largest room at least half of the way from the start to the
+// exit, the secret room is the smallest dead end, and the treasure rooms are the two dead ends farthest from the start.
+func NewDefaultRoleRules() RoleRules {
+
+	return RoleRules{
+
+		Start: RoleRule{
+			Tag:   RoleStart,
+			Count: 1,
+			Score: func(info RoomInfo) float64 { return -info.DistanceFromCenter },
+		},
+
+		Rules: []RoleRule{
+			{
+				Tag:    RoleExit,
+				Count:  1,
+				Filter: func(info RoomInfo) bool { return info.HopsFromStart > 0 },
+				Score:  func(info RoomInfo) float64 { return float64(info.HopsFromStart) },
+			},
+			{
+				Tag:   RoleBoss,
+				Count: 1,
+				Filter: func(info RoomInfo) bool {
+					return info.HopsFromStart > 0 && info.HopsFromStart*2 >= info.MaxHopsFromStart
+				},
+				Score: func(info RoomInfo) float64 { return float64(info.Area) },
+			},
+			{
+				Tag:    RoleSecret,
+				Count:  1,
+				Filter: func(info RoomInfo) bool { return info.DeadEnd && info.HopsFromStart > 0 },
+				Score:  func(info RoomInfo) float64 { return -float64(info.Area) },
+			},
+			{
+				Tag:    RoleTreasure,
+				Count:  2,
+				Filter: func(info RoomInfo) bool { return info.DeadEnd && info.HopsFromStart > 0 },
+				Score:  func(info RoomInfo) float64 { return float64(info.HopsFromStart) },
+			},
+		},
+	}
+
+}
+
+// AssignRoles tags the rooms provided with gameplay roles according to the rules given, and returns the rooms given each tag.
+// The rooms can come from any generator, as long as their Connected lists describe how they're connected.
+func AssignRoles(rooms []*Room, rules RoleRules) map[string][]*Room {
+
+	assigned := map[string][]*Room{}
+
+	if len(rooms) == 0 {
+		return assigned
+	}
+
+	infos := make([]RoomInfo, len(rooms))
+
+	minX, minY := math.MaxInt32, math.MaxInt32
+	maxX, maxY := math.MinInt32, math.MinInt32
+
+	for _, room := range rooms {
+		if room.X < minX {
+			minX = room.X
+		}
+		if room.Y < minY {
+			minY = room.Y
+		}
+		if room.X+room.W > maxX {
+			maxX = room.X + room.W
+		}
+		if room.Y+room.H > maxY {
+			maxY = room.Y + room.H
+		}
+	}
+
+	center := Position{(minX + maxX) / 2, (minY + maxY) / 2}
+	deadEnds := roomSet(DeadEndRooms(rooms))
+	chokepoints := roomSet(ArticulationRooms(rooms))
+
+	for i, room := range rooms {
+		infos[i] = RoomInfo{
+			Room:               room,
+			Area:               room.Area(),
+			HopsFromStart:      -1,
+			DeadEnd:            deadEnds[room],
+			Chokepoint:         chokepoints[room],
+			DistanceFromCenter: room.Center().DistanceTo(center),
+		}
+	}
+
+	apply := func(rule RoleRule) {
+
+		candidates := []RoomInfo{}
+		scores := []float64{}
+
+		for _, info := range infos {
+			if (rule.AllowTagged || len(info.Room.Tags) == 0) && (rule.Filter == nil || rule.Filter(info)) {
+				candidates = append(candidates, info)
+				if rule.Score != nil {
+					scores = append(scores, rule.Score(info))
+				}
+			}
+		}
+
+		if rule.Score != nil {
+			sort.Stable(byScore{candidates, scores})
+		}
+
+		if rule.Count > 0 && len(candidates) > rule.Count {
+			candidates = candidates[:rule.Count]
+		}
+
+		for _, info := range candidates {
+			info.Room.AddTag(rule.Tag)
+			assigned[rule.Tag] = append(assigned[rule.Tag], info.Room)
+		}
+
+	}
+
+	apply(rules.Start)
+
+	if starts := assigned[rules.Start.Tag]; len(starts) > 0 {
+		hops := starts[0].HopsFrom()
+		_, most := starts[0].Farthest()
+		for i := range infos {
+			if h, reachable := hops[infos[i].Room]; reachable {
+				infos[i].HopsFromStart = h
+			}
+			infos[i].MaxHopsFromStart = most
+		}
+	}
+
+	for _, rule := range rules.Rules {
+		apply(rule)
+	}
+
+	return assigned
+
+}
+
+// byScore sorts RoomInfos by their scores, highest first.
+type byScore struct {
+	infos  []RoomInfo
+	scores []float64
+}
+
+func (s byScore) Len() int           { return len(s.infos) }
+func (s byScore) Less(i, j int) bool { return s.scores[i] > s.scores[j] }
+func (s byScore) Swap(i, j int) {
+	s.infos[i], s.infos[j] = s.infos[j], s.infos[i]
+	s.scores[i], s.scores[j] = s.scores[j], s.scores[i]
+}
@@ -0,0 +1,56 @@
+package dngn
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestAssignRoles checks that the default roles are each given out, and that rooms that were already tagged are left alone.
+func TestAssignRoles(t *testing.T) {
+
+	layout := NewLayout(60, 40)
+	layout.RNG = rand.New(rand.NewSource(1))
+
+	result, err := layout.GenerateBSP(NewDefaultBSPOptions())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tagged := map[*Room]bool{}
+	for i, room := range result.Rooms {
+		if i%2 == 0 {
+			room.AddTag("custom")
+			tagged[room] = true
+		}
+	}
+
+	assigned := AssignRoles(result.Rooms, NewDefaultRoleRules())
+
+	for _, role := range []string{RoleStart, RoleExit} {
+		if len(assigned[role]) != 1 {
+			t.Errorf("expected one %s room, got %d", role, len(assigned[role]))
+		}
+	}
+
+	if len(assigned[RoleStart]) > 0 && len(assigned[RoleExit]) > 0 && assigned[RoleStart][0] == assigned[RoleExit][0] {
+		t.Errorf("the start and exit are the same room")
+	}
+
+	for role, rooms := range assigned {
+		for _, room := range rooms {
+			if tagged[room] {
+				t.Errorf("room that was already tagged was given the %s role", role)
+			}
+			if !room.HasTag(role) {
+				t.Errorf("room returned for the %s role wasn't tagged with it", role)
+			}
+		}
+	}
+
+	for room := range tagged {
+		if len(room.Tags) != 1 {
+			t.Errorf("room that was already tagged has tags %v", room.Tags)
+		}
+	}
+
+}
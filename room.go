@@ -0,0 +1,404 @@
+package dngn
+
+// Room represents a room generated by one of the Layout's Generate functions (a partition from GenerateBSP(), a rectangle from
+// GenerateRandomRooms(), a cave from GenerateDrunkWalk(), and so on). X, Y, W, and H are the room's bounds, while Cells contains the
+// room's floor cells, which may not fill the bounds (for example, in caves, or in BSP rooms, whose bounds include their top and left walls).
+type Room struct {
+	X, Y, W, H  int       // X, Y, Width, and Height of the Room.
+	Cells       Selection // The Room's floor cells.
+	Connected   []*Room   // The Rooms this room is connected to; each room appears once.
+	Doors       []Door    // The doors leading out of this room; From is always this room.
+	Traversable bool      // Whether the Room is traversable when using CountHopsTo().
+	Tags        []string  // Tags (like gameplay roles assigned through AssignRoles()) attached to the room; each tag appears once.
+}
+
+// Door is a door between two Rooms. X and Y are the door's position in the Layout, From is the room the door leads out of, and To is
+// the room it leads into. Doors between the walls of two rooms (as in GenerateBSP() when not carving rooms) are listed in both rooms'
+// Doors, with From and To swapped.
+type Door struct {
+	X, Y     int
+	From, To *Room
+}
+
+// BSPRoom and BSPDoor are the names Room and Door had when only GenerateBSP() returned rooms; they're kept so existing code still compiles.
+type (
+	BSPRoom = Room
+	BSPDoor = Door
+)
+
+// NewBSPRoom returns a new Room; it's the same as NewRoom().
+func NewBSPRoom(x, y, w, h int) *Room {
+	return NewRoom(x, y, w, h)
+}
+
+// connectRooms connects the two rooms provided through a door at the position given, adding the door to both rooms. It returns
+// the door going from a to b.
+func connectRooms(a, b *Room, position Position) Door {
+
+	a.connect(b)
+
+	a.Doors = append(a.Doors, Door{X: position.X, Y: position.Y, From: a, To: b})
+	b.Doors = append(b.Doors, Door{X: position.X, Y: position.Y, From: b, To: a})
+
+	return a.Doors[len(a.Doors)-1]
+
+}
+
+// connect adds each Room to the other's Connected list, if they're not already connected.
+func (bsp *Room) connect(other *Room) {
+
+	for _, connected := range bsp.Connected {
+		if connected == other {
+			return
+		}
+	}
+
+	bsp.Connected = append(bsp.Connected, other)
+	other.Connected = append(other.Connected, bsp)
+
+}
+
+// NewRoom returns a new, traversable Room with the bounds provided, and no cells or connections.
+func NewRoom(x, y, w, h int) *Room {
+	return &Room{
+		X:           x,
+		Y:           y,
+		W:           w,
+		H:           h,
+		Connected:   []*Room{},
+		Doors:       []Door{},
+		Traversable: true,
+	}
+}
+
+// Area returns the area of the Room (width * height).
+func (bsp *Room) Area() int {
+	return bsp.W * bsp.H
+}
+
+// MinSize returns the minimum size of the room.
+func (bsp *Room) MinSize() int {
+	if bsp.W < bsp.H {
+		return bsp.W
+	}
+	return bsp.H
+}
+
+func (bsp *Room) Center() Position {
+	return Position{bsp.X + bsp.W/2, bsp.Y + bsp.H/2}
+}
+
+// Contains returns if the given position lies within the Room's area.
+func (bsp *Room) Contains(x, y int) bool {
+	return x >= bsp.X && y >= bsp.Y && x < bsp.X+bsp.W && y < bsp.Y+bsp.H
+}
+
+// CountHopsTo will count the number of hops to go from one room to another, by hopping through connected neighbors. If no traversable link between the two rooms found, CountHopsTo will return -1.
+func (bsp *Room) CountHopsTo(room *Room) int {
+
+	toCheck := append([]*Room{}, bsp)
+	perRoomHopCount := map[*Room]int{
+		bsp: 0,
+	}
+
+	for len(toCheck) > 0 {
+
+		next := toCheck[0]
+
+		if next == room {
+			return perRoomHopCount[next]
+		}
+
+		toCheck = toCheck[1:]
+
+		if !next.Traversable {
+			continue
+		}
+
+		for _, connected := range next.Connected {
+
+			if _, exists := perRoomHopCount[connected]; !exists {
+				toCheck = append(toCheck, connected)
+				perRoomHopCount[connected] = perRoomHopCount[next] + 1
+			}
+
+		}
+
+	}
+
+	return -1
+}
+
+// HasTag returns if the Room has the tag provided.
+func (bsp *Room) HasTag(tag string) bool {
+	for _, t := range bsp.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// AddTag adds the tag provided to the Room, if it doesn't have it already.
+func (bsp *Room) AddTag(tag string) {
+	if !bsp.HasTag(tag) {
+		bsp.Tags = append(bsp.Tags, tag)
+	}
+}
+
+// RemoveTag removes the tag provided from the Room.
+func (bsp *Room) RemoveTag(tag string) {
+	for i, t := range bsp.Tags {
+		if t == tag {
+			bsp.Tags = append(bsp.Tags[:i], bsp.Tags[i+1:]...)
+			return
+		}
+	}
+}
+
+// Disconnect removes the Room from any of its neighbors' Connected lists, breaking the link between them.
+func (bsp *Room) Disconnect() {
+
+	for _, neighbor := range bsp.Connected {
+		for i, me := range neighbor.Connected {
+			if me == bsp {
+				neighbor.Connected = append(neighbor.Connected[:i], neighbor.Connected[i+1:]...)
+				break
+			}
+		}
+	}
+
+	bsp.Connected = []*Room{}
+}
+
+// Necessary returns if the Room is necessary to facilitate traversal from its neighbors to the rest of the BSP Layout; that is, if
+// it's a dead end, or if removing it would cut some of the rooms it's connected to off from each other (see ArticulationRooms()).
+// Traversable isn't considered.
+func (bsp *Room) Necessary() bool {
+
+	// If you only have one neighbor, then you're necessary
+	if len(bsp.Connected) == 1 {
+		return true
+	}
+
+	component := []*Room{}
+	toCheck := []*Room{bsp}
+	found := map[*Room]bool{bsp: true}
+
+	for len(toCheck) > 0 {
+		next := toCheck[0]
+		toCheck = toCheck[1:]
+		component = append(component, next)
+		for _, connected := range next.Connected {
+			if !found[connected] {
+				found[connected] = true
+				toCheck = append(toCheck, connected)
+			}
+		}
+	}
+
+	return newRoomGraphSearch(component).articulation[bsp]
+
+}
+
+// areaCells returns a Selection of the cells in the given area of the Layout that have the value provided.
+func (layout *Layout) areaCells(x, y, w, h int, value rune) Selection {
+
+	selection := Selection{Layout: layout, Cells: map[Position]bool{}}
+
+	for cy := y; cy < y+h; cy++ {
+		for cx := x; cx < x+w; cx++ {
+			if cx >= 0 && cy >= 0 && cx < layout.Width && cy < layout.Height && layout.Get(cx, cy) == value {
+				selection.Cells[Position{cx, cy}] = true
+			}
+		}
+	}
+
+	return selection
+
+}
+
+// regionGraph returns a RoomGraph with a Room for each separate contiguous area of the value provided (see Layout.Regions()). Each
+// room's bounds are the bounding box of its area.
+func (layout *Layout) regionGraph(value rune) *RoomGraph {
+
+	graph := &RoomGraph{Rooms: []*Room{}, Doors: []Door{}}
+
+	for _, region := range layout.Regions(value, false) {
+
+		x, y := layout.Width, layout.Height
+		x2, y2 := 0, 0
+
+		for cell := range region.Cells {
+			if cell.X < x {
+				x = cell.X
+			}
+			if cell.Y < y {
+				y = cell.Y
+			}
+			if cell.X+1 > x2 {
+				x2 = cell.X + 1
+			}
+			if cell.Y+1 > y2 {
+				y2 = cell.Y + 1
+			}
+		}
+
+		room := NewRoom(x, y, x2-x, y2-y)
+		room.Cells = region
+		graph.Rooms = append(graph.Rooms, room)
+
+	}
+
+	return graph
+
+}
+
+// connectRoomsThroughFloor connects rooms whose cells overlap or touch, as well as rooms joined by cells of the floor value provided
+// that don't belong to any room (like corridors).
+func (layout *Layout) connectRoomsThroughFloor(rooms []*Room, floor rune) {
+
+	// Cells are visited in order (rather than by ranging over the Selections) so that the rooms' Connected lists are always in the same order.
+	cellsOf := func(room *Room) []Position {
+		cells := []Position{}
+		for y := room.Y; y < room.Y+room.H; y++ {
+			for x := room.X; x < room.X+room.W; x++ {
+				if room.Cells.Contains(x, y) {
+					cells = append(cells, Position{x, y})
+				}
+			}
+		}
+		return cells
+	}
+
+	owners := map[Position][]*Room{}
+
+	for _, room := range rooms {
+		for _, cell := range cellsOf(room) {
+			owners[cell] = append(owners[cell], room)
+		}
+	}
+
+	for _, room := range rooms {
+
+		visited := map[Position]bool{}
+		toCheck := []Position{}
+
+		for _, cell := range cellsOf(room) {
+			visited[cell] = true
+			toCheck = append(toCheck, cell)
+			for _, owner := range owners[cell] {
+				if owner != room {
+					room.connect(owner)
+				}
+			}
+		}
+
+		for len(toCheck) > 0 {
+
+			next := toCheck[0]
+			toCheck = toCheck[1:]
+
+			for _, dir := range [][2]int{{1, 0}, {-1, 0}, {0, 1}, {0, -1}} {
+
+				neighbor := Position{next.X + dir[0], next.Y + dir[1]}
+
+				if visited[neighbor] {
+					continue
+				}
+
+				visited[neighbor] = true
+
+				if len(owners[neighbor]) > 0 {
+					for _, owner := range owners[neighbor] {
+						if owner != room {
+							room.connect(owner)
+						}
+					}
+				} else if neighbor.X >= 0 && neighbor.Y >= 0 && neighbor.X < layout.Width && neighbor.Y < layout.Height && layout.Get(neighbor.X, neighbor.Y) == floor {
+					toCheck = append(toCheck, neighbor)
+				}
+
+			}
+
+		}
+
+	}
+
+}
+
+// RoomGraph is a set of Rooms generated by one of the Layout's Generate functions, along with the doors between them. The rooms'
+// Connected lists describe how they're connected; not every connection has a door (for example, rooms joined by corridors without
+// doors in GenerateRandomRooms()).
+type RoomGraph struct {
+	Rooms []*Room // The rooms generated.
+	Doors []Door  // Every door generated. Doors shared by two rooms are only listed once.
+}
+
+// Connected returns if every room in the RoomGraph can be reached from every other room by going through the rooms' Connected lists.
+func (graph *RoomGraph) Connected() bool {
+
+	if len(graph.Rooms) == 0 {
+		return true
+	}
+
+	reached := map[*Room]bool{graph.Rooms[0]: true}
+	toCheck := []*Room{graph.Rooms[0]}
+
+	for len(toCheck) > 0 {
+
+		next := toCheck[0]
+		toCheck = toCheck[1:]
+
+		for _, connected := range next.Connected {
+			if !reached[connected] {
+				reached[connected] = true
+				toCheck = append(toCheck, connected)
+			}
+		}
+
+	}
+
+	return len(reached) == len(graph.Rooms)
+
+}
+
+// RoomAt returns the first room in the RoomGraph with a floor cell at the position given, or nil if there isn't one.
+func (graph *RoomGraph) RoomAt(x, y int) *Room {
+	for _, room := range graph.Rooms {
+		if room.Cells.Contains(x, y) {
+			return room
+		}
+	}
+	return nil
+}
+
+// DeadEnds returns the rooms in the RoomGraph that are only connected to one other room (see DeadEndRooms()).
+func (graph *RoomGraph) DeadEnds() []*Room {
+	return DeadEndRooms(graph.Rooms)
+}
+
+// Chokepoints returns the rooms in the RoomGraph that would cut off other rooms if they were removed (see ArticulationRooms()).
+func (graph *RoomGraph) Chokepoints() []*Room {
+	return ArticulationRooms(graph.Rooms)
+}
+
+// Bridges returns the connections in the RoomGraph that are the only way between the rooms on either side (see BridgeConnections()).
+func (graph *RoomGraph) Bridges() [][2]*Room {
+	return BridgeConnections(graph.Rooms)
+}
+
+// HasCycles returns if there are any loops in the RoomGraph (see HasCycles()).
+func (graph *RoomGraph) HasCycles() bool {
+	return HasCycles(graph.Rooms)
+}
+
+// Diameter returns the largest number of hops it takes to get from any room in the RoomGraph to any other room (see RoomDiameter()).
+func (graph *RoomGraph) Diameter() int {
+	return RoomDiameter(graph.Rooms)
+}
+
+// AssignRoles assigns roles to the rooms in the RoomGraph (see AssignRoles()).
+func (graph *RoomGraph) AssignRoles(rules RoleRules) map[string][]*Room {
+	return AssignRoles(graph.Rooms, rules)
+}
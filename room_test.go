@@ -0,0 +1,94 @@
+package dngn
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestRoomGraphs checks that the RoomGraphs returned by the cave generators describe the maps they generated: each floor cell
+// belongs to exactly one room.
+func TestRoomGraphs(t *testing.T) {
+
+	// Caves cover every floor cell, while the corridors between random rooms don't belong to any of them.
+	generators := map[string]func(layout *Layout) (*RoomGraph, error){
+		"drunk": func(layout *Layout) (*RoomGraph, error) {
+			return layout.GenerateDrunkWalk(' ', 'x', 0.4)
+		},
+		"dla": func(layout *Layout) (*RoomGraph, error) {
+			return layout.GenerateDLA(NewDefaultDLAOptions())
+		},
+		"cellular": func(layout *Layout) (*RoomGraph, error) {
+			return layout.GenerateCellular(NewDefaultCellularOptions())
+		},
+	}
+
+	for name, generate := range generators {
+
+		layout := NewLayout(60, 40)
+		layout.RNG = rand.New(rand.NewSource(1))
+
+		graph, err := generate(layout)
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+
+		if len(graph.Rooms) == 0 {
+			t.Errorf("%s: no rooms", name)
+		}
+
+		owners := map[Position]int{}
+
+		for _, room := range graph.Rooms {
+			for cell := range room.Cells.Cells {
+				owners[cell]++
+				if layout.Get(cell.X, cell.Y) != ' ' {
+					t.Errorf("%s: room cell %v isn't floor", name, cell)
+				}
+			}
+		}
+
+		for y := 0; y < layout.Height; y++ {
+			for x := 0; x < layout.Width; x++ {
+				if layout.Get(x, y) == ' ' && owners[Position{x, y}] != 1 {
+					t.Errorf("%s: floor cell %d, %d is in %d rooms", name, x, y, owners[Position{x, y}])
+				}
+			}
+		}
+
+	}
+
+}
+
+// TestRandomRoomGraph checks that the rooms returned by GenerateRandomRooms() are on the map, and connected when they should be.
+func TestRandomRoomGraph(t *testing.T) {
+
+	layout := NewLayout(60, 40)
+	layout.RNG = rand.New(rand.NewSource(1))
+
+	graph, err := layout.GenerateRandomRooms(' ', 'x', 6, 3, 3, 6, 6, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(graph.Rooms) == 0 {
+		t.Fatal("no rooms")
+	}
+
+	for _, room := range graph.Rooms {
+		for cell := range room.Cells.Cells {
+			if layout.Get(cell.X, cell.Y) != ' ' {
+				t.Errorf("room cell %v isn't floor", cell)
+			}
+		}
+	}
+
+	if !graph.Connected() {
+		t.Errorf("the rooms aren't connected")
+	}
+
+	if regions := openRegions(layout, 'x'); regions != 1 {
+		t.Errorf("the rooms are split into %d separate areas", regions)
+	}
+
+}
@@ -1,7 +1,5 @@
 package dngn
 
-import "math/rand"
-
 // A Selection represents a selection of cell positions in the Layout's data array, and can be filtered down and manipulated
 // using the functions on the Selection struct. You can use Selections to manipulate a
 type Selection struct {
@@ -47,12 +45,22 @@ func (selection Selection) None() Selection {
 	return newSelection
 }
 
-// FilterByPercentage selects the provided percentage (from 0 - 1) of the cells curently in the Selection.
+// FilterByPercentage selects the provided percentage (from 0 - 1) of the cells curently in the Selection. The cells are picked using
+// the Layout's RNG, so the result is reproducible when the Layout's RNG is seeded.
 func (selection Selection) FilterByPercentage(percentage float32) Selection {
 
-	return selection.FilterBy(func(x, y int) bool {
-		return rand.Float32() <= percentage
-	})
+	newSelection := selection.None()
+
+	// Cells are visited in order so that the RNG is always consumed in the same way for the same Selection.
+	for y := 0; y < selection.Layout.Height; y++ {
+		for x := 0; x < selection.Layout.Width; x++ {
+			if selection.Contains(x, y) && selection.Layout.RNG.Float32() <= percentage {
+				newSelection.Cells[Position{x, y}] = true
+			}
+		}
+	}
+
+	return newSelection
 
 }
 
@@ -94,37 +102,16 @@ func (selection Selection) Remove(other Selection) Selection {
 
 // FilterByNeighbor returns a filtered Selection of the cells that are surrounded at least by minNeighborCount neighbors with a value of
 // neighborValue. If diagonals is true, then diagonals are also checked. If atMost is true, then FilterByNeighbor will only
-// work if there's at MOST that many neighbors.
+// work if there's at MOST that many neighbors. Neighbors follow the Layout's Grid (see Layout.Neighbors()), so on hex grids, each
+// cell has 6 neighbors, and diagonals is ignored.
 func (selection Selection) FilterByNeighbor(neighborValue rune, minNeighborCount int, diagonals bool, atMost bool) Selection {
 
 	return selection.FilterBy(func(x, y int) bool {
 
 		n := 0
 
-		if selection.Layout.Get(x-1, y) == neighborValue {
-			n++
-		}
-		if selection.Layout.Get(x+1, y) == neighborValue {
-			n++
-		}
-		if selection.Layout.Get(x, y-1) == neighborValue {
-			n++
-		}
-		if selection.Layout.Get(x, y+1) == neighborValue {
-			n++
-		}
-
-		if diagonals {
-			if selection.Layout.Get(x-1, y-1) == neighborValue {
-				n++
-			}
-			if selection.Layout.Get(x+1, y-1) == neighborValue {
-				n++
-			}
-			if selection.Layout.Get(x-1, y+1) == neighborValue {
-				n++
-			}
-			if selection.Layout.Get(x+1, y+1) == neighborValue {
+		for _, neighbor := range selection.Layout.Neighbors(x, y, diagonals) {
+			if selection.Layout.Get(neighbor.X, neighbor.Y) == neighborValue {
 				n++
 			}
 		}
@@ -183,6 +170,7 @@ func (selection Selection) Select(num int) []Position {
 
 // Expand expands the selection outwards by the distance value provided. Diagonal indicates if the expansion should happen
 // diagonally as well, or just on the cardinal 4 directions. If a negative value is given for distance, it shrinks the selection.
+// On hex grids, the selection grows (or shrinks) by a hex in each of the 6 directions, and diagonal is ignored.
 func (selection Selection) Expand(distance int, diagonal bool) Selection {
 
 	newSelection := selection.Clone()
@@ -210,7 +198,16 @@ func (selection Selection) Expand(distance int, diagonal bool) Selection {
 
 		for cp := range cells {
 
-			if shrinking {
+			if shrinking && selection.Layout.Grid.IsHex() {
+
+				for _, neighbor := range selection.Layout.Neighbors(cp.X, cp.Y, false) {
+					if !newSelection.Contains(neighbor.X, neighbor.Y) {
+						toRemove = append(toRemove, cp)
+						break
+					}
+				}
+
+			} else if shrinking {
 
 				if !newSelection.Contains(cp.X-1, cp.Y) || !newSelection.Contains(cp.X+1, cp.Y) || !newSelection.Contains(cp.X, cp.Y-1) || !newSelection.Contains(cp.X, cp.Y+1) {
 
@@ -224,16 +221,8 @@ func (selection Selection) Expand(distance int, diagonal bool) Selection {
 
 			} else {
 
-				newSelection.AddPosition(cp.X-1, cp.Y)
-				newSelection.AddPosition(cp.X+1, cp.Y)
-				newSelection.AddPosition(cp.X, cp.Y-1)
-				newSelection.AddPosition(cp.X, cp.Y+1)
-
-				if diagonal {
-					newSelection.AddPosition(cp.X-1, cp.Y-1)
-					newSelection.AddPosition(cp.X-1, cp.Y+1)
-					newSelection.AddPosition(cp.X+1, cp.Y-1)
-					newSelection.AddPosition(cp.X+1, cp.Y+1)
+				for _, neighbor := range selection.Layout.Neighbors(cp.X, cp.Y, diagonal) {
+					newSelection.AddPosition(neighbor.X, neighbor.Y)
 				}
 
 			}
@@ -263,12 +252,7 @@ func (selection Selection) Invert() Selection {
 
 // Contains returns a boolean indicating if the specified cell is in the list of cells contained in the selection.
 func (selection *Selection) Contains(x, y int) bool {
-	for c := range selection.Cells {
-		if c.X == x && c.Y == y {
-			return true
-		}
-	}
-	return false
+	return selection.Cells[Position{x, y}]
 }
 
 // Fill fills the cells in the Selection with the rune provided.
@@ -0,0 +1,495 @@
+package dngn
+
+import (
+	"context"
+	"fmt"
+)
+
+// The tags given to the rooms returned from GenerateTown().
+const (
+	TownStreets  = "streets"
+	TownPlaza    = "plaza"
+	TownBuilding = "building"
+)
+
+// TownOptions configures Layout.GenerateTown().
+type TownOptions struct {
+	GroundValue rune // The rune for open ground between buildings (yards and gaps).
+	StreetValue rune // The rune for streets.
+	PlazaValue  rune // The rune for the plaza.
+	WallValue   rune // The rune for building walls.
+	FloorValue  rune // The rune for building floors.
+	DoorValue   rune // The rune for doors, both inside of buildings and leading out to the streets.
+
+	StreetWidth int // How wide the streets are, in cells. A street runs around the edges of the town, as well as between blocks.
+	BlockSize   int // The minimum width and height of a block (the area between streets); blocks are up to about twice this size.
+	LotSize     int // The minimum width of a lot (the piece of a block a building stands on) along its street.
+
+	BuildingChance  float32 // The chance (0 - 1) of each lot having a building on it; lots without buildings are left as open ground.
+	LotMargin       int     // How much open ground is left between a building and the edges of its lot.
+	MinimumRoomSize int     // The minimum width and height of the rooms inside of a building; buildings are split into rooms until they can't be.
+
+	Plaza bool // If the block closest to the center of the town should be a plaza, rather than lots.
+}
+
+// NewDefaultTownOptions returns the default TownOptions: two-cell wide streets ('.'), a plaza (':'), and buildings with walls ('x'),
+// floors (' '), and doors ('#') standing on open ground (',').
+func NewDefaultTownOptions() TownOptions {
+	return TownOptions{
+		GroundValue:     ',',
+		StreetValue:     '.',
+		PlazaValue:      ':',
+		WallValue:       'x',
+		FloorValue:      ' ',
+		DoorValue:       '#',
+		StreetWidth:     2,
+		BlockSize:       14,
+		LotSize:         7,
+		BuildingChance:  0.85,
+		LotMargin:       1,
+		MinimumRoomSize: 3,
+		Plaza:           true,
+	}
+}
+
+// validate returns an error if the options can't be used to generate a town in the Layout provided.
+func (options TownOptions) validate(layout *Layout) error {
+
+	if options.StreetWidth < 1 {
+		return invalidArgument("GenerateTown", "street width must be at least 1, got %d", options.StreetWidth)
+	}
+
+	if options.BlockSize < 1 || options.LotSize < 1 {
+		return invalidArgument("GenerateTown", "block and lot sizes must be at least 1, got %d and %d", options.BlockSize, options.LotSize)
+	}
+
+	if options.LotMargin < 0 {
+		return invalidArgument("GenerateTown", "lot margin can't be negative, got %d", options.LotMargin)
+	}
+
+	if options.MinimumRoomSize < 1 {
+		return invalidArgument("GenerateTown", "minimum room size must be at least 1, got %d", options.MinimumRoomSize)
+	}
+
+	// This is written so that NaN fails it too.
+	if !(options.BuildingChance >= 0 && options.BuildingChance <= 1) {
+		return invalidArgument("GenerateTown", "building chance must be between 0 and 1, got %v", options.BuildingChance)
+	}
+
+	if minSize := options.BlockSize + options.StreetWidth*2; layout.Width < minSize || layout.Height < minSize {
+		return invalidArgument("GenerateTown", "a %dx%d layout is too small for a single block; it needs to be at least %dx%d", layout.Width, layout.Height, minSize, minSize)
+	}
+
+	return nil
+
+}
+
+// TownBlock is an area between streets generated by Layout.GenerateTown(), which is divided into lots (unless it's the plaza).
+type TownBlock struct {
+	X, Y, W, H int
+	Lots       []*TownLot
+}
+
+// TownLot is a piece of a TownBlock that faces a street, and might have a building on it. Facing is the direction from the lot to
+// the street it faces (for example, {0, -1} for lots facing a street above them).
+type TownLot struct {
+	X, Y, W, H int
+	Facing     Position
+	Building   *Building // The building on the lot, or nil if the lot is open ground.
+}
+
+// Building is a building generated by Layout.GenerateTown(). X, Y, W, and H are the building's bounds, including its outer walls.
+// Rooms are the rooms inside of the building, and Door is the front door, leading out of one of the rooms (Door.From) to the
+// streets (Door.To).
+type Building struct {
+	X, Y, W, H int
+	Rooms      []*Room
+	Door       Door
+}
+
+// TownResult is the result of generating a town through Layout.GenerateTown(). The RoomGraph contains a single room for all of the
+// streets and open ground (tagged TownStreets), the plaza (tagged TownPlaza), if there is one, and every room inside of the
+// buildings (tagged TownBuilding).
+type TownResult struct {
+	RoomGraph
+	Streets   *Room
+	Plaza     *Room // The plaza, or nil if there isn't one.
+	Blocks    []*TownBlock
+	Buildings []*Building
+}
+
+// GenerateTown lays out a town in the Layout. The town is split up into blocks by a network of streets, with one more street
+// running around the town's edges. The block closest to the center becomes a plaza, while the rest are subdivided into lots that
+// each face a street. Most lots get a building, which is split up into rooms connected by doors (much like GenerateBSP()), with a
+// front door facing the street.
+// It returns the generated town, or an error if the options are invalid (see TownOptions). Every returned building's front Door
+// connects one of its Rooms to the streets; if a building somehow has no space for one of its doors, an error wrapping
+// ErrCannotConverge is returned instead of a town with rooms that can't be reached.
+// GenerateTown is the same as GenerateTownContext() with a background context.
+func (layout *Layout) GenerateTown(options TownOptions) (*TownResult, error) {
+	return layout.GenerateTownContext(context.Background(), options)
+}
+
+// GenerateTownContext works like GenerateTown(), but stops and returns an error if the context is cancelled or the Layout's
+// StepBudget runs out before generation finishes.
+func (layout *Layout) GenerateTownContext(ctx context.Context, options TownOptions) (*TownResult, error) {
+
+	if err := layout.validateSize("GenerateTown"); err != nil {
+		return nil, err
+	}
+
+	if err := options.validate(layout); err != nil {
+		return nil, err
+	}
+
+	gen := layout.generation(ctx, "town")
+
+	layout.Select().Fill(options.StreetValue)
+
+	result := &TownResult{
+		RoomGraph: RoomGraph{Rooms: []*Room{}, Doors: []Door{}},
+		Streets:   NewRoom(0, 0, layout.Width, layout.Height),
+		Blocks:    []*TownBlock{},
+		Buildings: []*Building{},
+	}
+
+	result.Streets.AddTag(TownStreets)
+	result.Rooms = append(result.Rooms, result.Streets)
+
+	// Split the town up into blocks, leaving a street between each pair of blocks.
+	sw := options.StreetWidth
+	toSplit := []*TownBlock{{X: sw, Y: sw, W: layout.Width - sw*2, H: layout.Height - sw*2}}
+
+	for len(toSplit) > 0 {
+
+		block := toSplit[0]
+		toSplit = toSplit[1:]
+
+		canSplitX := block.W >= options.BlockSize*2+sw
+		canSplitY := block.H >= options.BlockSize*2+sw
+
+		if !canSplitX && !canSplitY {
+			result.Blocks = append(result.Blocks, block)
+			continue
+		}
+
+		vertical := canSplitX && (!canSplitY || block.W >= block.H)
+
+		var a, b *TownBlock
+		var position int
+
+		if vertical {
+			position = block.X + options.BlockSize + layout.RNG.Intn(block.W-options.BlockSize*2-sw+1)
+			a = &TownBlock{X: block.X, Y: block.Y, W: position - block.X, H: block.H}
+			b = &TownBlock{X: position + sw, Y: block.Y, W: block.X + block.W - position - sw, H: block.H}
+		} else {
+			position = block.Y + options.BlockSize + layout.RNG.Intn(block.H-options.BlockSize*2-sw+1)
+			a = &TownBlock{X: block.X, Y: block.Y, W: block.W, H: position - block.Y}
+			b = &TownBlock{X: block.X, Y: position + sw, W: block.W, H: block.Y + block.H - position - sw}
+		}
+
+		if err := gen.step(GenerationStep{Kind: StepSplit, X: block.X, Y: block.Y, W: block.W, H: block.H, Vertical: vertical, SplitPosition: position}); err != nil {
+			return nil, err
+		}
+
+		toSplit = append(toSplit, a, b)
+
+	}
+
+	var plazaBlock *TownBlock
+
+	if options.Plaza {
+		center := layout.Center()
+		closest := 0.0
+		for _, block := range result.Blocks {
+			if d := (Position{block.X + block.W/2, block.Y + block.H/2}).DistanceTo(center); plazaBlock == nil || d < closest {
+				plazaBlock = block
+				closest = d
+			}
+		}
+	}
+
+	for _, block := range result.Blocks {
+
+		if block == plazaBlock {
+
+			result.Plaza = NewRoom(block.X, block.Y, block.W, block.H)
+			result.Plaza.AddTag(TownPlaza)
+			layout.fillArea(block.X, block.Y, block.W, block.H, options.PlazaValue)
+			result.Plaza.Cells = layout.areaCells(block.X, block.Y, block.W, block.H, options.PlazaValue)
+			result.Plaza.connect(result.Streets)
+			result.Rooms = append(result.Rooms, result.Plaza)
+
+			if err := gen.step(GenerationStep{Kind: StepRoom, X: block.X, Y: block.Y, W: block.W, H: block.H, Room: result.Plaza}); err != nil {
+				return nil, err
+			}
+
+			continue
+
+		}
+
+		layout.fillArea(block.X, block.Y, block.W, block.H, options.GroundValue)
+
+		layout.divideTownBlock(block, options)
+
+		for _, lot := range block.Lots {
+
+			if layout.RNG.Float32() >= options.BuildingChance {
+				continue
+			}
+
+			building, err := layout.placeBuilding(gen, lot, result, options)
+			if err != nil {
+				return nil, err
+			}
+
+			if building != nil {
+				lot.Building = building
+				result.Buildings = append(result.Buildings, building)
+			}
+
+		}
+
+	}
+
+	result.Streets.Cells = layout.areaCells(0, 0, layout.Width, layout.Height, options.StreetValue).Add(layout.areaCells(0, 0, layout.Width, layout.Height, options.GroundValue))
+
+	return result, nil
+
+}
+
+// divideTownBlock splits the block provided up into lots. Lots are laid out in rows along the block's longer sides, so that each
+// lot faces a street; deep enough blocks get two rows, back to back.
+func (layout *Layout) divideTownBlock(block *TownBlock, options TownOptions) {
+
+	horizontal := block.W >= block.H
+
+	length, depth := block.W, block.H
+	if !horizontal {
+		length, depth = block.H, block.W
+	}
+
+	// Each row is described by its offset into the block across its depth, how deep it is, and which way it faces.
+	type row struct {
+		offset, depth int
+		facing        int
+	}
+
+	rows := []row{{0, depth, -1}}
+	if depth >= options.LotSize*2 {
+		rows = []row{{0, depth / 2, -1}, {depth / 2, depth - depth/2, 1}}
+	}
+
+	for _, r := range rows {
+
+		for start := 0; start < length; {
+
+			lotLength := length - start
+			if lotLength >= options.LotSize*2 {
+				lotLength = options.LotSize + layout.RNG.Intn(minInt(options.LotSize, lotLength-options.LotSize*2+1))
+			}
+
+			lot := &TownLot{}
+
+			if horizontal {
+				lot.X, lot.Y, lot.W, lot.H = block.X+start, block.Y+r.offset, lotLength, r.depth
+				lot.Facing = Position{0, r.facing}
+			} else {
+				lot.X, lot.Y, lot.W, lot.H = block.X+r.offset, block.Y+start, r.depth, lotLength
+				lot.Facing = Position{r.facing, 0}
+			}
+
+			block.Lots = append(block.Lots, lot)
+			start += lotLength
+
+		}
+
+	}
+
+}
+
+// placeBuilding places a building on the lot provided, splits it up into rooms, and adds its doors, returning the building. If the
+// lot is too small for a building, it returns nil; if a wall has no space for a door, it returns an error.
+func (layout *Layout) placeBuilding(gen *generation, lot *TownLot, result *TownResult, options TownOptions) (*Building, error) {
+
+	building := &Building{
+		X:     lot.X + options.LotMargin,
+		Y:     lot.Y + options.LotMargin,
+		W:     lot.W - options.LotMargin*2,
+		H:     lot.H - options.LotMargin*2,
+		Rooms: []*Room{},
+	}
+
+	if building.W < options.MinimumRoomSize+2 || building.H < options.MinimumRoomSize+2 {
+		return nil, nil
+	}
+
+	layout.fillArea(building.X, building.Y, building.W, building.H, options.WallValue)
+	layout.fillArea(building.X+1, building.Y+1, building.W-2, building.H-2, options.FloorValue)
+
+	// Split the building up into rooms; each area includes its walls, so neighboring areas share the wall between them.
+	type area struct{ x, y, w, h int }
+	type wall struct {
+		x, y, length int
+		vertical     bool
+	}
+
+	toSplit := []area{{building.X, building.Y, building.W, building.H}}
+	walls := []wall{}
+	minSize := options.MinimumRoomSize
+
+	for len(toSplit) > 0 {
+
+		a := toSplit[0]
+		toSplit = toSplit[1:]
+
+		canSplitX := a.w >= minSize*2+3
+		canSplitY := a.h >= minSize*2+3
+
+		if !canSplitX && !canSplitY {
+			room := NewRoom(a.x+1, a.y+1, a.w-2, a.h-2)
+			room.AddTag(TownBuilding)
+			building.Rooms = append(building.Rooms, room)
+			continue
+		}
+
+		if canSplitX && (!canSplitY || a.w >= a.h) {
+			x := a.x + minSize + 1 + layout.RNG.Intn(a.w-minSize*2-2)
+			walls = append(walls, wall{x, a.y + 1, a.h - 2, true})
+			toSplit = append(toSplit, area{a.x, a.y, x - a.x + 1, a.h}, area{x, a.y, a.x + a.w - x, a.h})
+		} else {
+			y := a.y + minSize + 1 + layout.RNG.Intn(a.h-minSize*2-2)
+			walls = append(walls, wall{a.x + 1, y, a.w - 2, false})
+			toSplit = append(toSplit, area{a.x, a.y, a.w, y - a.y + 1}, area{a.x, y, a.w, a.y + a.h - y})
+		}
+
+	}
+
+	for _, w := range walls {
+		if w.vertical {
+			layout.fillArea(w.x, w.y, 1, w.length, options.WallValue)
+		} else {
+			layout.fillArea(w.x, w.y, w.length, 1, options.WallValue)
+		}
+	}
+
+	for _, room := range building.Rooms {
+
+		room.Cells = layout.areaCells(room.X, room.Y, room.W, room.H, options.FloorValue)
+		result.Rooms = append(result.Rooms, room)
+
+		if err := gen.step(GenerationStep{Kind: StepRoom, X: room.X, Y: room.Y, W: room.W, H: room.H, Room: room}); err != nil {
+			return nil, err
+		}
+
+	}
+
+	roomAt := func(position Position) *Room {
+		for _, room := range building.Rooms {
+			if room.Contains(position.X, position.Y) {
+				return room
+			}
+		}
+		return nil
+	}
+
+	// addDoor places a door on one of the wall cells given that has floor on both sides (the sides being the cell offset by
+	// the direction provided in either direction), connecting the rooms on each side; outside of the building, the streets stand
+	// in for the room.
+	addDoor := func(cells []Position, across Position) error {
+
+		candidates := []Position{}
+
+		for _, cell := range cells {
+			inside := layout.Get(cell.X+across.X, cell.Y+across.Y) == options.FloorValue
+			outside := layout.Get(cell.X-across.X, cell.Y-across.Y)
+			if inside && (outside == options.FloorValue || outside == options.GroundValue || outside == options.StreetValue) {
+				candidates = append(candidates, cell)
+			}
+		}
+
+		// Rooms are never split closer than a cell to their walls' ends, so every wall should have space for a door. If one
+		// didn't, though, the building would be left with rooms that can't be reached, or a front door with nil rooms.
+		if len(candidates) == 0 {
+			return fmt.Errorf("dngn: GenerateTown: the building at %d, %d has no space for a door: %w", building.X, building.Y, ErrCannotConverge)
+		}
+
+		door := candidates[layout.RNG.Intn(len(candidates))]
+		from := roomAt(Position{door.X + across.X, door.Y + across.Y})
+		to := roomAt(Position{door.X - across.X, door.Y - across.Y})
+
+		if to == nil {
+			to = result.Streets
+		}
+
+		layout.Set(door.X, door.Y, options.DoorValue)
+		connectRooms(from, to, door)
+		result.Doors = append(result.Doors, from.Doors[len(from.Doors)-1], to.Doors[len(to.Doors)-1])
+
+		if to == result.Streets {
+			building.Door = from.Doors[len(from.Doors)-1]
+		}
+
+		return gen.step(GenerationStep{Kind: StepDoor, X: door.X, Y: door.Y, W: 1, H: 1, Room: from})
+
+	}
+
+	for _, w := range walls {
+
+		cells := []Position{}
+		across := Position{0, 1}
+
+		for i := 0; i < w.length; i++ {
+			if w.vertical {
+				cells = append(cells, Position{w.x, w.y + i})
+				across = Position{1, 0}
+			} else {
+				cells = append(cells, Position{w.x + i, w.y})
+			}
+		}
+
+		if err := addDoor(cells, across); err != nil {
+			return nil, err
+		}
+
+	}
+
+	// The front door goes on the wall facing the lot's street, leading inwards (the opposite way from the street).
+	front := []Position{}
+	across := Position{-lot.Facing.X, -lot.Facing.Y}
+
+	switch lot.Facing {
+	case Position{0, -1}:
+		for x := building.X + 1; x < building.X+building.W-1; x++ {
+			front = append(front, Position{x, building.Y})
+		}
+	case Position{0, 1}:
+		for x := building.X + 1; x < building.X+building.W-1; x++ {
+			front = append(front, Position{x, building.Y + building.H - 1})
+		}
+	case Position{-1, 0}:
+		for y := building.Y + 1; y < building.Y+building.H-1; y++ {
+			front = append(front, Position{building.X, y})
+		}
+	case Position{1, 0}:
+		for y := building.Y + 1; y < building.Y+building.H-1; y++ {
+			front = append(front, Position{building.X + building.W - 1, y})
+		}
+	}
+
+	if err := addDoor(front, across); err != nil {
+		return nil, err
+	}
+
+	return building, nil
+
+}
+
+// fillArea fills the given area of the Layout with the value provided.
+func (layout *Layout) fillArea(x, y, w, h int, value rune) {
+	for cy := y; cy < y+h; cy++ {
+		for cx := x; cx < x+w; cx++ {
+			layout.Set(cx, cy, value)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package dngn
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// TestGenerateTown checks that the same seed generates the same town, that every building has a front door out to the streets,
+// and that every room can be reached from the streets.
+func TestGenerateTown(t *testing.T) {
+
+	generate := func(seed int64, options TownOptions) (*Layout, *TownResult) {
+
+		layout := NewLayout(80, 60)
+		layout.RNG = rand.New(rand.NewSource(seed))
+
+		result, err := layout.GenerateTown(options)
+		if err != nil {
+			t.Fatalf("seed %d: %v", seed, err)
+		}
+
+		return layout, result
+
+	}
+
+	// No margin and the smallest rooms pack buildings right up against the streets and each other.
+	packed := NewDefaultTownOptions()
+	packed.LotMargin = 0
+	packed.MinimumRoomSize = 1
+	packed.BuildingChance = 1
+
+	for _, options := range []TownOptions{NewDefaultTownOptions(), packed} {
+
+		for seed := int64(1); seed <= 3; seed++ {
+
+			first, result := generate(seed, options)
+			second, _ := generate(seed, options)
+
+			if first.DataToString() != second.DataToString() {
+				t.Errorf("seed %d: the same seed generated different towns", seed)
+			}
+
+			if len(result.Buildings) == 0 {
+				t.Errorf("seed %d: the town has no buildings", seed)
+			}
+
+			for _, building := range result.Buildings {
+
+				door := building.Door
+
+				if door.From == nil || door.To != result.Streets {
+					t.Errorf("seed %d: the building at %d, %d has no front door to the streets", seed, building.X, building.Y)
+					continue
+				}
+
+				if !roomSet(building.Rooms)[door.From] {
+					t.Errorf("seed %d: the front door of the building at %d, %d leads out of another building", seed, building.X, building.Y)
+				}
+
+				if value := first.Get(door.X, door.Y); value != options.DoorValue {
+					t.Errorf("seed %d: the front door at %d, %d is drawn as %q", seed, door.X, door.Y, value)
+				}
+
+			}
+
+			if hops := result.Streets.HopsFrom(); len(hops) != len(result.Rooms) {
+				t.Errorf("seed %d: only %d of %d rooms can be reached from the streets", seed, len(hops), len(result.Rooms))
+			}
+
+		}
+
+	}
+
+}
+
+// TestGenerateTownInvalid checks that options the town can't be generated with (including a NaN building chance) are rejected.
+func TestGenerateTownInvalid(t *testing.T) {
+
+	for name, set := range map[string]func(options *TownOptions){
+		"building chance of NaN": func(options *TownOptions) { options.BuildingChance = float32(math.NaN()) },
+		"building chance of 2":   func(options *TownOptions) { options.BuildingChance = 2 },
+		"no street width":        func(options *TownOptions) { options.StreetWidth = 0 },
+		"blocks too big":         func(options *TownOptions) { options.BlockSize = 100 },
+	} {
+
+		options := NewDefaultTownOptions()
+		set(&options)
+
+		if _, err := NewLayout(80, 60).GenerateTown(options); !errors.Is(err, ErrInvalidArgument) {
+			t.Errorf("%s: expected ErrInvalidArgument, got %v", name, err)
+		}
+
+	}
+
+}
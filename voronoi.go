@@ -0,0 +1,176 @@
+package dngn
+
+import (
+	"math"
+)
+
+// PoissonDisc picks points from the cells in the Selection so that no two points are closer than minDistance to each other, and no
+// more points fit anywhere in the Selection. Unlike FilterByPercentage(), which picks cells independently of each other and so
+// leaves clumps and gaps, the points end up evenly (but still randomly) spread out, making them useful for scattering decorations
+// or picking sites for Voronoi(). The points are picked using the Layout's RNG, so the result is reproducible when the Layout's RNG
+// is seeded.
+func (selection Selection) PoissonDisc(minDistance float64) []Position {
+
+	cells := []Position{}
+
+	// Cells are gathered in order so that shuffling them with the RNG gives the same result for the same Selection.
+	for y := 0; y < selection.Layout.Height; y++ {
+		for x := 0; x < selection.Layout.Width; x++ {
+			if selection.Contains(x, y) {
+				cells = append(cells, Position{x, y})
+			}
+		}
+	}
+
+	selection.Layout.RNG.Shuffle(len(cells), func(i, j int) { cells[i], cells[j] = cells[j], cells[i] })
+
+	// This is written so that a NaN distance returns every cell too.
+	if !(minDistance > 0) {
+		return cells
+	}
+
+	// Points are sorted into buckets minDistance cells across, so only the points in neighboring buckets need to be checked.
+	bucketSize := math.Ceil(minDistance)
+	buckets := map[Position][]Position{}
+	points := []Position{}
+
+	for _, cell := range cells {
+
+		bucket := Position{int(float64(cell.X) / bucketSize), int(float64(cell.Y) / bucketSize)}
+		fits := true
+
+		for by := bucket.Y - 1; by <= bucket.Y+1 && fits; by++ {
+			for bx := bucket.X - 1; bx <= bucket.X+1 && fits; bx++ {
+				for _, point := range buckets[Position{bx, by}] {
+					if point.DistanceTo(cell) < minDistance {
+						fits = false
+						break
+					}
+				}
+			}
+		}
+
+		if fits {
+			points = append(points, cell)
+			buckets[bucket] = append(buckets[bucket], cell)
+		}
+
+	}
+
+	return points
+
+}
+
+// FilterByPoissonDisc filters the Selection down to points picked using PoissonDisc(), so that no two selected cells are closer than
+// minDistance to each other.
+func (selection Selection) FilterByPoissonDisc(minDistance float64) Selection {
+
+	newSelection := selection.None()
+
+	for _, point := range selection.PoissonDisc(minDistance) {
+		newSelection.Cells[point] = true
+	}
+
+	return newSelection
+
+}
+
+// Voronoi partitions the cells in the Selection between the sites provided, returning a Selection for each site (in the same
+// order as the sites) containing the cells that are closer to it than to any other site. Cells that are equally close to several
+// sites go to the first of them. This is useful for territory maps or splitting a cave into irregular chambers; sites can be
+// picked with PoissonDisc(), and evened out with RelaxSites().
+func (selection Selection) Voronoi(sites []Position) []Selection {
+
+	regions := make([]Selection, len(sites))
+	for i := range regions {
+		regions[i] = selection.None()
+	}
+
+	if len(sites) == 0 {
+		return regions
+	}
+
+	for cell := range selection.Cells {
+		regions[nearestSite(sites, cell)].Cells[cell] = true
+	}
+
+	return regions
+
+}
+
+// RelaxSites performs Lloyd relaxation on the sites provided, returning the relaxed sites. Each iteration, every site is moved to
+// the center of its Voronoi region (see Voronoi()), which evens out the sizes and shapes of the regions; a few iterations are
+// usually enough. If the center of a region isn't in the Selection (as can happen with irregular Selections), the site is moved
+// to the region's cell that's closest to the center instead. Sites whose regions are empty aren't moved.
+func (selection Selection) RelaxSites(sites []Position, iterations int) []Position {
+
+	sites = append([]Position{}, sites...)
+
+	for i := 0; i < iterations; i++ {
+
+		moved := false
+
+		for s, region := range selection.Voronoi(sites) {
+
+			if len(region.Cells) == 0 {
+				continue
+			}
+
+			sumX, sumY := 0, 0
+			for cell := range region.Cells {
+				sumX += cell.X
+				sumY += cell.Y
+			}
+
+			center := Position{
+				int(math.Round(float64(sumX) / float64(len(region.Cells)))),
+				int(math.Round(float64(sumY) / float64(len(region.Cells)))),
+			}
+
+			if !region.Contains(center.X, center.Y) {
+				closest := math.MaxFloat64
+				best := center
+				for cell := range region.Cells {
+					// Ties are broken by position, so that the result doesn't depend on the map's iteration order.
+					if d := cell.DistanceTo(center); d < closest || (d == closest && (cell.Y < best.Y || (cell.Y == best.Y && cell.X < best.X))) {
+						closest = d
+						best = cell
+					}
+				}
+				center = best
+			}
+
+			if center != sites[s] {
+				sites[s] = center
+				moved = true
+			}
+
+		}
+
+		if !moved {
+			break
+		}
+
+	}
+
+	return sites
+
+}
+
+// nearestSite returns the index of the site closest to the cell provided (the first one, if several are equally close).
+func nearestSite(sites []Position, cell Position) int {
+
+	nearest := 0
+	nearestDistance := 0
+
+	for i, site := range sites {
+		dx, dy := site.X-cell.X, site.Y-cell.Y
+		if d := dx*dx + dy*dy; i == 0 || d < nearestDistance {
+			nearest = i
+			nearestDistance = d
+		}
+	}
+
+	return nearest
+
+}
@@ -0,0 +1,113 @@
+package dngn
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// TestPoissonDisc checks that the points are spread out and that no more points would fit, and that the same seed picks the same
+// points.
+func TestPoissonDisc(t *testing.T) {
+
+	generate := func(seed int64) (Selection, []Position) {
+
+		layout := NewLayout(40, 30)
+		layout.RNG = rand.New(rand.NewSource(seed))
+
+		// Only half of the Layout is selected, so the points shouldn't leave it.
+		selection := layout.Select().FilterByArea(0, 0, 20, 30)
+
+		return selection, selection.PoissonDisc(4)
+
+	}
+
+	for seed := int64(1); seed <= 3; seed++ {
+
+		selection, points := generate(seed)
+		_, again := generate(seed)
+
+		if len(points) != len(again) {
+			t.Fatalf("seed %d: the same seed picked %d and then %d points", seed, len(points), len(again))
+		}
+
+		for i, point := range points {
+
+			if point != again[i] {
+				t.Errorf("seed %d: the same seed picked different points", seed)
+				break
+			}
+
+			if !selection.Contains(point.X, point.Y) {
+				t.Errorf("seed %d: %v isn't in the Selection", seed, point)
+			}
+
+			for _, other := range points[i+1:] {
+				if point.DistanceTo(other) < 4 {
+					t.Errorf("seed %d: %v and %v are closer than 4 cells", seed, point, other)
+				}
+			}
+
+		}
+
+		for cell := range selection.Cells {
+			fits := true
+			for _, point := range points {
+				if cell.DistanceTo(point) < 4 {
+					fits = false
+					break
+				}
+			}
+			if fits {
+				t.Errorf("seed %d: another point would fit at %v", seed, cell)
+			}
+		}
+
+	}
+
+	layout := NewLayout(10, 10)
+	layout.RNG = rand.New(rand.NewSource(1))
+
+	if points := layout.Select().PoissonDisc(math.NaN()); len(points) != layout.Area() {
+		t.Errorf("a NaN distance picked %d points, rather than every cell", len(points))
+	}
+
+}
+
+// TestVoronoi checks that the regions split the Selection between the sites with no cells left over or shared, and that relaxing
+// the sites keeps them in the Selection.
+func TestVoronoi(t *testing.T) {
+
+	layout := NewLayout(40, 30)
+	layout.RNG = rand.New(rand.NewSource(1))
+
+	selection := layout.Select()
+	sites := selection.PoissonDisc(8)
+	relaxed := selection.RelaxSites(sites, 5)
+
+	for _, site := range relaxed {
+		if !selection.Contains(site.X, site.Y) {
+			t.Errorf("relaxed site %v isn't in the Selection", site)
+		}
+	}
+
+	for _, sites := range [][]Position{sites, relaxed} {
+
+		count := 0
+
+		for i, region := range selection.Voronoi(sites) {
+			count += len(region.Cells)
+			for cell := range region.Cells {
+				if nearest := nearestSite(sites, cell); nearest != i {
+					t.Errorf("%v is in region %d, but is closest to site %d", cell, i, nearest)
+				}
+			}
+		}
+
+		if count != len(selection.Cells) {
+			t.Errorf("the regions hold %d cells, rather than %d", count, len(selection.Cells))
+		}
+
+	}
+
+}